@@ -5,8 +5,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"math"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -43,7 +45,6 @@ func parseDescType(b *bytes.Buffer, arg Arg) (int, error) {
 	var rn int
 	var err error
 
-	log.Printf("desctype")
 	var selector string
 	if arg.Flag != nil {
 		selector = strconv.FormatUint(uint64(*arg.Flag), 10)
@@ -62,7 +63,6 @@ func parseDescType(b *bytes.Buffer, arg Arg) (int, error) {
 	}
 
 	if arg.Sym != nil {
-		log.Printf(">>>> sym")
 		n, err := writeSym(b, *arg.Sym)
 		rn += n
 		if err != nil {
@@ -71,7 +71,7 @@ func parseDescType(b *bytes.Buffer, arg Arg) (int, error) {
 	}
 
 	if selector != "" {
-		n, err := writeSym(b, *arg.Selector)
+		n, err := writeSym(b, selector)
 		rn += n
 		if err != nil {
 			return rn, err
@@ -92,6 +92,9 @@ func parseTwoSym(b *bytes.Buffer, arg Arg) (int, error) {
 
 	var selector string
 	var sym string
+	if arg.Sym == nil {
+		return 0, fmt.Errorf("missing symbol")
+	}
 	if arg.Size != nil {
 		selector = strconv.FormatUint(uint64(*arg.Size), 10)
 		//sym = *arg.Selector
@@ -124,6 +127,9 @@ func parseTwoSym(b *bytes.Buffer, arg Arg) (int, error) {
 func parseTwoSymReverse(b *bytes.Buffer, arg Arg) (int, error) {
 	var rn int
 
+	if arg.Sym == nil || arg.Selector == nil {
+		return 0, fmt.Errorf("missing symbol or selector")
+	}
 	sym := *arg.Selector
 	selector := *arg.Sym 
 	n, err := writeSym(b, selector)
@@ -144,13 +150,15 @@ func parseTwoSymReverse(b *bytes.Buffer, arg Arg) (int, error) {
 func parseSig(b *bytes.Buffer, arg Arg) (int, error) {
 	var rn int
 
-	n, err := writeSym(b, *arg.Sym)
-	rn += n
-	if err != nil {
-		return rn, err
+	if arg.Sym != nil {
+		n, err := writeSym(b, *arg.Sym)
+		rn += n
+		if err != nil {
+			return rn, err
+		}
 	}
 
-	n, err = writeSize(b, *arg.Size)
+	n, err := writeSize(b, *arg.Size)
 	rn += n
 	if err != nil {
 		return rn, err
@@ -168,6 +176,9 @@ func parseSig(b *bytes.Buffer, arg Arg) (int, error) {
 func parseSized(b *bytes.Buffer, arg Arg) (int, error) {
 	var rn int
 
+	if arg.Sym == nil {
+		return 0, fmt.Errorf("missing symbol")
+	}
 	n, err := writeSym(b, *arg.Sym)
 	rn += n
 	if err != nil {
@@ -196,6 +207,10 @@ func parseOne(op vm.Opcode, instruction *Instruction, w io.Writer) (int, error)
 		return n_out, err
 	}
 
+	if (op == vm.LOAD || op == vm.MAXAGE) && a.Size == nil {
+		return 0, fmt.Errorf("missing size")
+	}
+
 	// Catch Menu batch commands
 	if a.Desc != nil {
 		n, err := parseDescType(b, a)
@@ -208,7 +223,7 @@ func parseOne(op vm.Opcode, instruction *Instruction, w io.Writer) (int, error)
 
 	// Catch
 	if a.Selector != nil {
-		log.Printf("have selector %v", instruction)
+		Logg.Tracef("have selector", "instruction", instruction)
 		var n int
 		var err error
 		if op == vm.MOUT {
@@ -225,14 +240,14 @@ func parseOne(op vm.Opcode, instruction *Instruction, w io.Writer) (int, error)
 
 	// Catch CATCH, LOAD and twosyms with integer-as-string
 	if a.Size != nil {
-		log.Printf("have size %v", instruction)
+		Logg.Tracef("have size", "instruction", instruction)
 		if a.Flag != nil {
 			n, err := parseSig(b, a)
 			n_buf += n
 			if err != nil {
 				return n_out, err
 			}
-		} else if op == vm.LOAD {
+		} else if op == vm.LOAD || op == vm.MAXAGE {
 			n, err := parseSized(b, a)
 			n_buf += n
 			if err != nil {
@@ -384,20 +399,29 @@ func(bt *Batcher) MenuAdd(w io.Writer, code string, arg Arg) (int, error) {
 	var sym string
 	var display string
 	if arg.Desc != nil {
+		if arg.Sym == nil || arg.Selector == nil {
+			return 0, fmt.Errorf("missing target or selector")
+		}
 		sym = *arg.Sym
 		display = *arg.Desc
 		selector = *arg.Selector
 	} else if arg.Size != nil {
+		if arg.Selector == nil {
+			return 0, fmt.Errorf("missing display")
+		}
 		if arg.Sym != nil {
 			sym = *arg.Sym
 		}
 		selector = strconv.FormatUint(uint64(*arg.Size), 10)
 		display = *arg.Selector
 	} else {
+		if arg.Sym == nil || arg.Selector == nil {
+			return 0, fmt.Errorf("missing selector or display")
+		}
 		selector = *arg.Sym
 		display = *arg.Selector
 	}
-	log.Printf("menu processor add %v '%v' '%v' '%v'", code, selector, display, sym)
+	Logg.Tracef("menu processor add", "code", code, "selector", selector, "display", display, "sym", sym)
 	err := bt.menuProcessor.Add(code, selector, display, sym)
 	return 0, err
 }
@@ -408,47 +432,170 @@ func(bt *Batcher) Exit(w io.Writer) (int, error) {
 }
 
 // Parse one or more lines of assembly code, and write assembled bytecode to the provided writer.
+//
+// Every line is parsed, also after an error has been encountered. If any line fails, nothing is written to the writer, and the returned error is an Errors slice with one Error for every failed line.
+//
+// Macros are expanded before parsing. Since there is no filesystem to resolve them from, INCLUDE directives fail.
 func Parse(s string, w io.Writer) (int, error) {
-	rd := strings.NewReader(s)
-	ast, err := asmParser.Parse("file", rd)
+	pp := newPreprocessor(nil, "")
+	return parse(pp.process("", s), pp.errs, w, nil)
+}
+
+// ParseFile parses the assembly code in the given file, and write assembled bytecode to the provided writer.
+//
+// INCLUDE directives are resolved relative to the directory of the file.
+//
+// Same as Parse, with the file path included in errors.
+func ParseFile(fp string, w io.Writer) (int, error) {
+	dir, name := filepath.Split(fp)
+	if dir == "" {
+		dir = "."
+	}
+	return parseFS(os.DirFS(dir), name, dir, w)
+}
+
+// ParseFS parses the assembly code in the file with the given name in the filesystem, and write assembled bytecode to the provided writer.
+//
+// INCLUDE directives are resolved from the same filesystem, relative to the directory of the including file.
+//
+// Same as Parse, with the file name included in errors.
+func ParseFS(fsys fs.FS, name string, w io.Writer) (int, error) {
+	return parseFS(fsys, name, "", w)
+}
+
+// Includes returns the names of all files included by the file with the given name in the filesystem, directly or indirectly.
+//
+// Included files that cannot be read are not returned. Errors in the source are ignored, as they are reported when parsing the file.
+func Includes(fsys fs.FS, name string) ([]string, error) {
+	v, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	pp := newPreprocessor(fsys, "")
+	pp.process(name, string(v))
+	return pp.files, nil
+}
+
+func parseFS(fsys fs.FS, name string, prefix string, w io.Writer) (int, error) {
+	v, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return 0, err
 	}
+	pp := newPreprocessor(fsys, prefix)
+	return parse(pp.process(name, string(v)), pp.errs, w, nil)
+}
+
+// Positions assembles the assembly code in the given file, and returns the source position of every instruction in the resulting bytecode, in order.
+//
+// Includes, macros and flag names are resolved as with ParseFile. Instructions generated by menu batch commands are attributed to the line of the command they originate from, and the HALT between them to the last command of the batch.
+func Positions(fp string) ([]Position, error) {
+	var r []Position
+	dir, name := filepath.Split(fp)
+	if dir == "" {
+		dir = "."
+	}
+	fsys := os.DirFS(dir)
+	v, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	pp := newPreprocessor(fsys, dir)
+	_, err = parse(pp.process(name, string(v)), pp.errs, io.Discard, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	batch := Batcher{
-		
+// assemble preprocessed source lines.
+//
+// If pos is not nil, the source position of every assembled instruction is appended to it.
+func parse(lines []srcLine, errs Errors, w io.Writer, pos *[]Position) (int, error) {
+	var menu []srcLine
+	b := bytes.NewBuffer(nil)
+	batch := Batcher{}
+	exit := func() error {
+		n, err := batch.MenuExit(b)
+		if n > 0 && pos != nil {
+			*pos = append(*pos, menuPositions(menu)...)
+		}
+		return err
 	}
 
-	var rn int
-	for _, v := range ast.Instructions {
-		log.Printf("parsing line %v: %v", v.OpCode, v.OpArg)
-		op, ok := vm.OpcodeIndex[v.OpCode]
-		if !ok {
-			n, err := batch.MenuAdd(w, v.OpCode, v.OpArg)
-			rn += n
-			if err != nil {
-				return rn, err
+	for _, l := range lines {
+		ast, err := asmParser.ParseString(l.file, l.text + "\n")
+		if err != nil {
+			errs = append(errs, l.parseError(err))
+			continue
+		}
+		for _, v := range ast.Instructions {
+			Logg.Tracef("parsing line", "file", l.file, "line", l.line, "opcode", v.OpCode, "arg", v.OpArg)
+			op, ok := vm.OpcodeIndex[v.OpCode]
+			if !ok {
+				_, err := batch.MenuAdd(b, v.OpCode, v.OpArg)
+				if err != nil {
+					errs = append(errs, l.error("%s: %v", v.OpCode, err))
+					continue
+				}
+				menu = append(menu, l)
+				continue
 			}
-		} else {
-			n, err := batch.MenuExit(w)
+			err := exit()
 			if err != nil {
-				return rn, err
+				return 0, err
+			}
+			bi := bytes.NewBuffer(nil)
+			_, err = parseOne(op, v, bi)
+			if err == nil {
+				err = verify(bi.Bytes())
 			}
-			rn += n
-			n, err = parseOne(op, v, w)
-			rn += n
 			if err != nil {
-				return rn, err
+				errs = append(errs, l.error("invalid %s instruction: %v", v.OpCode, err))
+				continue
+			}
+			Logg.Tracef("assembled line", "file", l.file, "line", l.line, "bytes", bi.Len())
+			b.Write(bi.Bytes())
+			if pos != nil {
+				*pos = append(*pos, l.position())
 			}
-			log.Printf("wrote %v bytes for %v", n, v.OpArg)
 		}
 	}
-	n, err := batch.Exit(w)
-	rn += n
+	err := exit()
 	if err != nil {
-		return rn, err
+		return 0, err
 	}
-	rn += n
+	if len(errs) > 0 {
+		return 0, errs
+	}
+	return flush(b, w)
+}
 
-	return rn, err
+// positions of the instructions generated for the menu batch commands on the given lines.
+//
+// Menu items precede the HALT, and input matches follow it, in the order of the commands.
+func menuPositions(lines []srcLine) []Position {
+	var r []Position
+	if len(lines) == 0 {
+		return r
+	}
+	for _, v := range lines {
+		r = append(r, v.position())
+	}
+	r = append(r, lines[len(lines)-1].position())
+	for _, v := range lines {
+		r = append(r, v.position())
+	}
+	return r
+}
+
+// verify that the bytecode is exactly one valid instruction.
+func verify(b []byte) error {
+	_, b, err := vm.ParseInstruction(b)
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 {
+		return fmt.Errorf("%d trailing bytes", len(b))
+	}
+	return nil
 }
@@ -195,6 +195,32 @@ func TestParserSized(t *testing.T) {
 	}
 }
 
+func TestParseMaxAge(t *testing.T) {
+	var b []byte
+	b = vm.NewLine(b, vm.MAXAGE, []string{"foo"}, []byte{0x0e, 0x10}, nil)
+	s, err := vm.ToString(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "MAXAGE foo 3600\n" {
+		t.Fatalf("unexpected disassembly: %s", s)
+	}
+
+	r := bytes.NewBuffer(nil)
+	_, err = Parse(s, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.Bytes(), b) {
+		t.Fatalf("expected %x, got %x", b, r.Bytes())
+	}
+
+	_, err = Parse("MAXAGE foo\n", r)
+	if err == nil {
+		t.Fatalf("expected error on missing max age")
+	}
+}
+
 func TestParseDisplay(t *testing.T) {
 	var b []byte
 	b = vm.NewLine(b, vm.MOUT, []string{"foo", "baz_ba_zbaz"}, nil, nil)
@@ -413,3 +439,63 @@ func TestParserCapQuote(t *testing.T) {
 	}
 	_ = n
 }
+
+func TestParseCroak(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	s := "CROAK 8 1\n"
+	_, err := Parse(s, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := vm.NewLine(nil, vm.CROAK, nil, []byte{0x08}, []uint8{0x01})
+	if !bytes.Equal(b.Bytes(), expect) {
+		t.Fatalf("expected:\n\t%x\ngot:\n\t%x\n", expect, b)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	s := `LOAD foo 42
+MOUT foo
+
+# comment
+	INCMP 1
+HALT
+load bar 1
+LOAD bar x
+`
+	_, err := Parse(s, b)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if b.Len() > 0 {
+		t.Fatalf("expected no output on error, got %x", b)
+	}
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %T", err)
+	}
+	expect := []struct{
+		line int
+		col int
+	}{
+		{2, 1},
+		{5, 2},
+		{7, 1},
+		{8, 1},
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expect), len(errs), errs)
+	}
+	for i, v := range expect {
+		if errs[i].Line != v.line || errs[i].Column != v.col {
+			t.Fatalf("error %d: expected %d:%d, got %s", i, v.line, v.col, errs[i])
+		}
+	}
+	if errs[1].Snippet != "\tINCMP 1\n\t^" {
+		t.Fatalf("unexpected snippet:\n%s", errs[1].Snippet)
+	}
+	if errs[3].Error() != "8:1: invalid LOAD instruction: missing size" {
+		t.Fatalf("unexpected error: %s", errs[3])
+	}
+}
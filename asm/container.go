@@ -0,0 +1,154 @@
+package asm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/vm"
+)
+
+const (
+	// Version of the assembler, recorded in the bytecode container header.
+	VERSION = 2
+)
+
+// NewTable lists the symbols, flags and selectors referenced by the bytecode.
+//
+// Relative navigation targets are not considered symbols.
+func NewTable(b []byte) (*bytecode.Table, error) {
+	tb := &bytecode.Table{}
+	seen := make(map[string]bool)
+	seenFlags := make(map[uint32]bool)
+	addSym := func(s string) {
+		switch s {
+		case "_", "^", ".", ">", "<":
+			return
+		}
+		if !seen["sym:" + s] {
+			seen["sym:" + s] = true
+			tb.Symbols = append(tb.Symbols, s)
+		}
+	}
+	addSel := func(s string) {
+		if !seen["sel:" + s] {
+			seen["sel:" + s] = true
+			tb.Selectors = append(tb.Selectors, s)
+		}
+	}
+	addFlag := func(v uint32) {
+		if !seenFlags[v] {
+			seenFlags[v] = true
+			tb.Flags = append(tb.Flags, v)
+		}
+	}
+	for len(b) > 0 {
+		op, bb, err := vm.ParseOp(b)
+		if err != nil {
+			return nil, err
+		}
+		b = bb
+		switch op {
+		case vm.CATCH:
+			var sym string
+			var sig uint32
+			sym, sig, _, b, err = vm.ParseCatch(b)
+			addSym(sym)
+			addFlag(sig)
+		case vm.CROAK:
+			var sig uint32
+			sig, _, b, err = vm.ParseCroak(b)
+			addFlag(sig)
+		case vm.LOAD:
+			var sym string
+			sym, _, b, err = vm.ParseLoad(b)
+			addSym(sym)
+		case vm.MAXAGE:
+			var sym string
+			sym, _, b, err = vm.ParseMaxAge(b)
+			addSym(sym)
+		case vm.RELOAD:
+			var sym string
+			sym, b, err = vm.ParseReload(b)
+			addSym(sym)
+		case vm.MAP:
+			var sym string
+			sym, b, err = vm.ParseMap(b)
+			addSym(sym)
+		case vm.MOVE:
+			var sym string
+			sym, b, err = vm.ParseMove(b)
+			addSym(sym)
+		case vm.INCMP:
+			var sym string
+			var sel string
+			sym, sel, b, err = vm.ParseInCmp(b)
+			addSym(sym)
+			addSel(sel)
+		case vm.MOUT:
+			var sel string
+			_, sel, b, err = vm.ParseMOut(b)
+			addSel(sel)
+		case vm.MNEXT:
+			var sel string
+			_, sel, b, err = vm.ParseMNext(b)
+			addSel(sel)
+		case vm.MPREV:
+			var sel string
+			_, sel, b, err = vm.ParseMPrev(b)
+			addSel(sel)
+		case vm.HALT:
+			b, err = vm.ParseHalt(b)
+		case vm.MSINK:
+			b, err = vm.ParseMSink(b)
+		default:
+			return nil, fmt.Errorf("unhandled opcode: %v", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tb, nil
+}
+
+// SourceHash returns the sha256 hash of the source file with the given name in the filesystem, together with all files it includes.
+func SourceHash(fsys fs.FS, name string) ([bytecode.HASH_SIZE]byte, error) {
+	var r [bytecode.HASH_SIZE]byte
+	includes, err := Includes(fsys, name)
+	if err != nil {
+		return r, err
+	}
+	h := sha256.New()
+	for _, v := range append([]string{name}, includes...) {
+		b, err := fs.ReadFile(fsys, v)
+		if err != nil {
+			return r, err
+		}
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+		h.Write(b)
+		h.Write([]byte{0})
+	}
+	copy(r[:], h.Sum(nil))
+	return r, nil
+}
+
+// Container returns the bytecode in a container, with the hash of the source it was assembled from.
+//
+// If withTable is set, a table of the references in the bytecode is included.
+func Container(b []byte, sourceHash [bytecode.HASH_SIZE]byte, withTable bool) ([]byte, error) {
+	h := bytecode.Header{
+		VmVersion: vm.VERSION,
+		AsmVersion: VERSION,
+		SourceHash: sourceHash,
+	}
+	if withTable {
+		tb, err := NewTable(b)
+		if err != nil {
+			return nil, err
+		}
+		h.Table = tb
+	}
+	return bytecode.Encode(h, b), nil
+}
@@ -0,0 +1,75 @@
+package asm
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/vm"
+)
+
+func TestContainer(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	_, err := Parse(`LOAD foo 0
+MAP foo
+CATCH bar 8 1
+CROAK 9 0
+RELOAD foo
+MOUT next 1
+MOUT prev 0
+HALT
+INCMP baz 1
+INCMP _ 0
+INCMP bar *
+`, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sh [bytecode.HASH_SIZE]byte
+	sh[0] = 0x2a
+	c, err := Container(b.Bytes(), sh, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, code, err := bytecode.Decode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, b.Bytes()) {
+		t.Fatalf("expected code %x, got %x", b, code)
+	}
+	if h.VmVersion != vm.VERSION || h.AsmVersion != VERSION || h.SourceHash != sh {
+		t.Fatalf("unexpected header: %v", h)
+	}
+	expect := &bytecode.Table{
+		Symbols: []string{"foo", "bar", "baz"},
+		Flags: []uint32{8, 9},
+		Selectors: []string{"1", "0", "*"},
+	}
+	if !reflect.DeepEqual(h.Table, expect) {
+		t.Fatalf("expected %v, got %v", expect, h.Table)
+	}
+}
+
+func TestSourceHash(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.vis": &fstest.MapFile{Data: []byte(`INCLUDE "flags.vis"
+HALT
+`)},
+		"flags.vis": &fstest.MapFile{Data: []byte("FLAG foo 8\n")},
+	}
+	h, err := SourceHash(fsys, "root.vis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys["flags.vis"] = &fstest.MapFile{Data: []byte("FLAG foo 9\n")}
+	hh, err := SourceHash(fsys, "root.vis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == hh {
+		t.Fatalf("expected change in included file to change hash")
+	}
+}
@@ -0,0 +1,87 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+)
+
+// Error is an assembly error at a position in the source.
+type Error struct {
+	File string // Empty if the source is not a file.
+	Line int
+	Column int
+	Msg string
+	Snippet string // Source line, followed by a line with a caret marking the column.
+}
+
+func newError(name string, line int, col int, src string, msg string, args ...any) *Error {
+	if col == 0 {
+		col = len(src) - len(strings.TrimLeft(src, " \t")) + 1
+	}
+	return &Error{
+		File: name,
+		Line: line,
+		Column: col,
+		Msg: fmt.Sprintf(msg, args...),
+		Snippet: snippet(src, col),
+	}
+}
+
+// error from the participle parser, for a single line of source.
+func newParseError(name string, line int, src string, err error) *Error {
+	var col int
+	msg := err.Error()
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		col = perr.Position().Column
+		msg = perr.Message()
+	}
+	if col > len(src) + 1 {
+		col = len(src) + 1
+	}
+	return newError(name, line, col, src, "%s", msg)
+}
+
+// Error implements the error interface.
+//
+// The format is "[<file>:]<line>:<column>: <message>".
+func(e *Error) Error() string {
+	s := fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+	if e.File != "" {
+		s = e.File + ":" + s
+	}
+	return s
+}
+
+// Errors holds all errors found when assembling a source.
+type Errors []*Error
+
+// Error implements the error interface.
+//
+// Every error is followed by its source snippet.
+func(e Errors) Error() string {
+	var r []string
+	for _, v := range e {
+		r = append(r, v.Error() + "\n" + v.Snippet)
+	}
+	return strings.Join(r, "\n")
+}
+
+// the source line with a caret marking the column below it.
+func snippet(src string, col int) string {
+	var b strings.Builder
+	for i, c := range src {
+		if i >= col - 1 {
+			break
+		}
+		if c == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return src + "\n" + b.String() + "^"
+}
@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("asm")
 )
+
+func init() {
+	logging.Register("asm", &Logg)
+}
@@ -0,0 +1,373 @@
+package asm
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"git.defalsify.org/vise.git/state"
+	"git.defalsify.org/vise.git/vm"
+)
+
+const (
+	// Maximum depth of nested includes and macro expansions.
+	preprocessMaxDepth = 16
+)
+
+var (
+	identRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	paramRegex = regexp.MustCompile(`\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+)
+
+// single line of source after preprocessing, with its origin.
+type srcLine struct {
+	text string
+	file string
+	line int
+	via string // Description of the macro expansion the line originates from, if any.
+}
+
+// Position is the source file and line an assembled instruction originates from.
+type Position struct {
+	File string
+	Line int
+}
+
+// position of the line in its source file.
+//
+// Lines expanded from a macro are attributed to the macro body.
+func(l srcLine) position() Position {
+	return Position{
+		File: l.file,
+		Line: l.line,
+	}
+}
+
+// error at the position of the line.
+func(l srcLine) error(msg string, args ...any) *Error {
+	e := newError(l.file, l.line, 0, l.text, msg, args...)
+	e.Msg += l.via
+	return e
+}
+
+// parser error at the position of the line.
+func(l srcLine) parseError(err error) *Error {
+	e := newParseError(l.file, l.line, l.text, err)
+	e.Msg += l.via
+	return e
+}
+
+// macro definition.
+type macro struct {
+	name string
+	params []string
+	body []srcLine
+}
+
+// preprocessor resolves INCLUDE directives, expands macros and resolves flag names.
+//
+// A macro is defined with MACRO <name> [<param> ...] and ENDMACRO, and is expanded wherever a line starts with its name. Parameters are referred to in the body as $<param>.
+//
+// A flag name is declared with FLAG <name> <flag>, and may be used instead of the flag number in CATCH and CROAK.
+//
+// Macros and flag names are visible to all lines following the definition, also across included files.
+type preprocessor struct {
+	fsys fs.FS
+	prefix string
+	macros map[string]*macro
+	flags map[string]uint32
+	flagNames map[uint32]string
+	includes []string
+	files []string // All files included so far.
+	errs Errors
+}
+
+func newPreprocessor(fsys fs.FS, prefix string) *preprocessor {
+	return &preprocessor{
+		fsys: fsys,
+		prefix: prefix,
+		macros: make(map[string]*macro),
+		flags: make(map[string]uint32),
+		flagNames: make(map[uint32]string),
+	}
+}
+
+// the file name to use in errors.
+func(pp *preprocessor) display(name string) string {
+	if pp.prefix == "" || name == "" {
+		return name
+	}
+	return path.Join(pp.prefix, name)
+}
+
+// process returns the lines of the source with includes resolved and macros expanded.
+//
+// Blank lines and comments are omitted. Errors are collected in the preprocessor.
+func(pp *preprocessor) process(name string, src string) []srcLine {
+	var r []srcLine
+	var cur *macro
+	file := pp.display(name)
+	pp.includes = append(pp.includes, name)
+	defer func() {
+		pp.includes = pp.includes[:len(pp.includes)-1]
+	}()
+
+	for i, v := range strings.Split(src, "\n") {
+		l := srcLine{
+			text: strings.TrimRight(v, "\r"),
+			file: file,
+			line: i + 1,
+		}
+		f := strings.Fields(l.text)
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		if cur != nil {
+			switch f[0] {
+			case "ENDMACRO":
+				pp.macros[cur.name] = cur
+				cur = nil
+			case "MACRO":
+				pp.errs = append(pp.errs, l.error("MACRO inside definition of macro '%s'", cur.name))
+			default:
+				cur.body = append(cur.body, l)
+			}
+			continue
+		}
+		switch f[0] {
+		case "INCLUDE":
+			r = append(r, pp.include(name, l, f)...)
+		case "MACRO":
+			cur = pp.define(l, f)
+		case "ENDMACRO":
+			pp.errs = append(pp.errs, l.error("ENDMACRO without MACRO"))
+		default:
+			for _, v := range pp.expand(l, f, 0) {
+				ff := strings.Fields(v.text)
+				if ff[0] == "FLAG" {
+					pp.declare(v, ff)
+					continue
+				}
+				v, ok := pp.resolveFlag(v, ff)
+				if ok {
+					r = append(r, v)
+				}
+			}
+		}
+	}
+	if cur != nil {
+		pp.errs = append(pp.errs, &Error{
+			File: file,
+			Msg: fmt.Sprintf("missing ENDMACRO for macro '%s'", cur.name),
+		})
+	}
+	return r
+}
+
+// resolve an INCLUDE directive.
+func(pp *preprocessor) include(name string, l srcLine, f []string) []srcLine {
+	if len(f) != 2 {
+		pp.errs = append(pp.errs, l.error("INCLUDE expects one file name"))
+		return nil
+	}
+	if pp.fsys == nil {
+		pp.errs = append(pp.errs, l.error("INCLUDE not available without filesystem"))
+		return nil
+	}
+	fp := path.Join(path.Dir(name), strings.Trim(f[1], "\"'"))
+	for _, v := range pp.includes {
+		if v == fp {
+			pp.errs = append(pp.errs, l.error("recursive INCLUDE of '%s'", fp))
+			return nil
+		}
+	}
+	if len(pp.includes) > preprocessMaxDepth {
+		pp.errs = append(pp.errs, l.error("INCLUDE nested too deep"))
+		return nil
+	}
+	b, err := fs.ReadFile(pp.fsys, fp)
+	if err != nil {
+		pp.errs = append(pp.errs, l.error("INCLUDE failed: %v", err))
+		return nil
+	}
+	Logg.Debugf("include", "file", l.file, "line", l.line, "include", fp)
+	pp.addFile(fp)
+	return pp.process(fp, string(b))
+}
+
+// record an included file.
+func(pp *preprocessor) addFile(fp string) {
+	for _, v := range pp.files {
+		if v == fp {
+			return
+		}
+	}
+	pp.files = append(pp.files, fp)
+}
+
+// start a macro definition.
+//
+// An invalid definition is recorded as error, but is still returned to consume the body lines.
+func(pp *preprocessor) define(l srcLine, f []string) *macro {
+	m := &macro{}
+	if len(f) < 2 {
+		pp.errs = append(pp.errs, l.error("MACRO expects a name"))
+		return m
+	}
+	m.name = f[1]
+	m.params = f[2:]
+	if !identRegex.MatchString(m.name) {
+		pp.errs = append(pp.errs, l.error("invalid macro name '%s'", m.name))
+	}
+	_, isOp := vm.OpcodeIndex[m.name]
+	_, isBatch := batchCode[m.name]
+	switch {
+	case isOp, isBatch, m.name == "INCLUDE", m.name == "MACRO", m.name == "ENDMACRO", m.name == "FLAG":
+		pp.errs = append(pp.errs, l.error("macro name '%s' is reserved", m.name))
+	}
+	for _, v := range m.params {
+		if !identRegex.MatchString(v) {
+			pp.errs = append(pp.errs, l.error("invalid macro parameter '%s'", v))
+		}
+	}
+	return m
+}
+
+// expand the line if it is a macro invocation.
+func(pp *preprocessor) expand(l srcLine, f []string, depth int) []srcLine {
+	m, ok := pp.macros[f[0]]
+	if !ok {
+		return []srcLine{l}
+	}
+	if depth > preprocessMaxDepth {
+		pp.errs = append(pp.errs, l.error("macro '%s' nested too deep", m.name))
+		return nil
+	}
+	args := f[1:]
+	if len(args) != len(m.params) {
+		pp.errs = append(pp.errs, l.error("macro '%s' expects %d arguments, got %d", m.name, len(m.params), len(args)))
+		return nil
+	}
+	vals := make(map[string]string)
+	for i, v := range m.params {
+		vals[v] = args[i]
+	}
+	via := fmt.Sprintf(" (in macro '%s' used at %s:%d)", m.name, l.file, l.line)
+
+	var r []srcLine
+	for _, v := range m.body {
+		ok := true
+		v.text = paramRegex.ReplaceAllStringFunc(v.text, func(s string) string {
+			val, have := vals[s[1:]]
+			if !have {
+				ok = false
+				return s
+			}
+			return val
+		})
+		v.via = via + l.via
+		if !ok {
+			pp.errs = append(pp.errs, v.error("undefined macro parameter"))
+			continue
+		}
+		r = append(r, pp.expand(v, strings.Fields(v.text), depth + 1)...)
+	}
+	Logg.Tracef("expanded macro", "macro", m.name, "file", l.file, "line", l.line, "lines", len(r))
+	return r
+}
+
+// record a flag name declaration.
+//
+// Declaring the same name for the same flag more than once is allowed, so that flag definition files may be included from several files.
+func(pp *preprocessor) declare(l srcLine, f []string) {
+	if len(f) != 3 {
+		pp.errs = append(pp.errs, l.error("FLAG expects name and flag"))
+		return
+	}
+	name := f[1]
+	if !identRegex.MatchString(name) {
+		pp.errs = append(pp.errs, l.error("invalid flag name '%s'", name))
+		return
+	}
+	v, err := strconv.ParseUint(f[2], 10, 32)
+	if err != nil {
+		pp.errs = append(pp.errs, l.error("invalid flag '%s'", f[2]))
+		return
+	}
+	flag := uint32(v)
+	if flag < state.FLAG_USERSTART {
+		pp.errs = append(pp.errs, l.error("flag %d is reserved, user flags start at %d", flag, state.FLAG_USERSTART))
+		return
+	}
+	cur, ok := pp.flags[name]
+	if ok && cur != flag {
+		pp.errs = append(pp.errs, l.error("flag '%s' already declared as %d", name, cur))
+		return
+	}
+	other, ok := pp.flagNames[flag]
+	if ok && other != name {
+		pp.errs = append(pp.errs, l.error("flag %d already declared as '%s'", flag, other))
+		return
+	}
+	pp.flags[name] = flag
+	pp.flagNames[flag] = name
+	Logg.Tracef("declared flag", "name", name, "flag", flag)
+}
+
+// substitute a flag name in the signal argument of CATCH and CROAK with its flag number.
+//
+// Returns false if the name has not been declared.
+func(pp *preprocessor) resolveFlag(l srcLine, f []string) (srcLine, bool) {
+	var i int
+	switch f[0] {
+	case "CATCH":
+		i = 2
+	case "CROAK":
+		i = 1
+	default:
+		return l, true
+	}
+	if len(f) <= i || strings.HasPrefix(f[i], "#") {
+		return l, true
+	}
+	_, err := strconv.ParseUint(f[i], 10, 32)
+	if err == nil {
+		return l, true
+	}
+	flag, ok := pp.flags[f[i]]
+	if !ok {
+		pp.errs = append(pp.errs, l.error("undefined flag '%s'", f[i]))
+		return l, false
+	}
+	l.text = replaceField(l.text, i, strconv.FormatUint(uint64(flag), 10))
+	return l, true
+}
+
+// replace the whitespace separated field at the given index, leaving the rest of the line intact.
+func replaceField(s string, idx int, val string) string {
+	var start int
+	n := -1
+	inField := false
+	for i, c := range s {
+		if unicode.IsSpace(c) {
+			if inField && n == idx {
+				return s[:start] + val + s[i:]
+			}
+			inField = false
+			continue
+		}
+		if !inField {
+			inField = true
+			n++
+			start = i
+		}
+	}
+	if inField && n == idx {
+		return s[:start] + val
+	}
+	return s
+}
@@ -0,0 +1,272 @@
+package asm
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"git.defalsify.org/vise.git/state"
+	"git.defalsify.org/vise.git/vm"
+)
+
+func TestParseFSInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.vis": &fstest.MapFile{Data: []byte(`INCLUDE "lib/menu.vis"
+LOAD foo 0
+suspended
+MAP foo
+backhome 0 9
+`)},
+		"lib/menu.vis": &fstest.MapFile{Data: []byte(`INCLUDE "catch.vis"
+
+# standard back and home menu
+MACRO backhome back home
+MOUT back $back
+MOUT home $home
+HALT
+INCMP _ $back
+INCMP ^ $home
+ENDMACRO
+`)},
+		"lib/catch.vis": &fstest.MapFile{Data: []byte(`MACRO suspended
+CATCH suspended 9 1
+ENDMACRO
+`)},
+	}
+	b := bytes.NewBuffer(nil)
+	_, err := ParseFS(fsys, "root.vis", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := bytes.NewBuffer(nil)
+	_, err = Parse(`LOAD foo 0
+CATCH suspended 9 1
+MAP foo
+MOUT back 0
+MOUT home 9
+HALT
+INCMP _ 0
+INCMP ^ 9
+`, expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), expect.Bytes()) {
+		t.Fatalf("expected:\n\t%x\ngot:\n\t%x", expect, b)
+	}
+}
+
+func TestParseMacroErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.vis": &fstest.MapFile{Data: []byte(`MACRO broken x
+LOAD $x
+MOUT $y 1
+ENDMACRO
+broken foo
+broken
+INCLUDE "root.vis"
+INCLUDE "nope.vis"
+MACRO HALT
+ENDMACRO
+`)},
+	}
+	_, err := ParseFS(fsys, "root.vis", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	errs := err.(Errors)
+	expect := []string{
+		"root.vis:3:1: undefined macro parameter (in macro 'broken' used at root.vis:5)",
+		"root.vis:6:1: macro 'broken' expects 1 arguments, got 0",
+		"root.vis:7:1: recursive INCLUDE of 'root.vis'",
+		"root.vis:8:1: INCLUDE failed: open nope.vis: file does not exist",
+		"root.vis:9:1: macro name 'HALT' is reserved",
+		"root.vis:2:1: invalid LOAD instruction: missing size (in macro 'broken' used at root.vis:5)",
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(expect), len(errs), errs)
+	}
+	for i, v := range errs {
+		if v.Error() != expect[i] {
+			t.Fatalf("error %d: expected\n%s\ngot\n%s", i, expect[i], v)
+		}
+	}
+
+	_, err = Parse("INCLUDE \"foo.vis\"\n", nil)
+	if err == nil || !strings.Contains(err.Error(), "without filesystem") {
+		t.Fatalf("expected include error, got %v", err)
+	}
+}
+
+func TestParseFlagNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.vis": &fstest.MapFile{Data: []byte(`INCLUDE "flags.vis"
+INCLUDE "flags.vis"
+LOAD foo 0
+CATCH suspended is_suspended 1
+CATCH terms  has_accepted	0 # not yet accepted
+CROAK is_suspended 1
+CROAK 9 0
+`)},
+		"flags.vis": &fstest.MapFile{Data: []byte(`FLAG is_suspended 8
+FLAG has_accepted 9
+`)},
+	}
+	b := bytes.NewBuffer(nil)
+	_, err := ParseFS(fsys, "root.vis", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := bytes.NewBuffer(nil)
+	_, err = Parse(`LOAD foo 0
+CATCH suspended 8 1
+CATCH terms 9 0
+CROAK 8 1
+CROAK 9 0
+`, expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), expect.Bytes()) {
+		t.Fatalf("expected:\n\t%x\ngot:\n\t%x", expect, b)
+	}
+}
+
+func TestParseFlagErrors(t *testing.T) {
+	src := `FLAG foo 8
+FLAG foo 9
+FLAG bar 8
+FLAG baz 3
+FLAG
+CATCH quux xyzzy 1
+MACRO FLAG
+ENDMACRO
+`
+	_, err := Parse(src, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	errs := err.(Errors)
+	expect := []string{
+		"2:1: flag 'foo' already declared as 8",
+		"3:1: flag 8 already declared as 'foo'",
+		"4:1: flag 3 is reserved, user flags start at 8",
+		"5:1: FLAG expects name and flag",
+		"6:1: undefined flag 'xyzzy'",
+		"7:1: macro name 'FLAG' is reserved",
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expect), len(errs), errs)
+	}
+	for i, v := range expect {
+		if errs[i].Error() != v {
+			t.Fatalf("expected error '%s', got '%s'", v, errs[i].Error())
+		}
+	}
+}
+
+func TestParseFlagRegistry(t *testing.T) {
+	fr := state.NewFlagRegistry()
+	fr.MustAdd("accepted")
+	suspended := fr.MustAdd("suspended")
+	flags := bytes.NewBuffer(nil)
+	_, err := fr.WriteTo(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"root.vis": &fstest.MapFile{Data: []byte(`INCLUDE "flags.vis"
+CATCH blocked suspended 1
+`)},
+		"flags.vis": &fstest.MapFile{Data: flags.Bytes()},
+	}
+	b := bytes.NewBuffer(nil)
+	_, err = ParseFS(fsys, "root.vis", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := vm.NewLine(nil, vm.CATCH, []string{"blocked"}, []byte{uint8(suspended)}, []uint8{1})
+	if !bytes.Equal(b.Bytes(), expect) {
+		t.Fatalf("expected:\n\t%x\ngot:\n\t%x", expect, b)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.vis": &fstest.MapFile{Data: []byte(`INCLUDE "lib/menu.vis"
+INCLUDE "flags.vis"
+INCLUDE "lib/menu.vis"
+HALT
+`)},
+		"flags.vis": &fstest.MapFile{Data: []byte("FLAG foo 8\n")},
+		"lib/menu.vis": &fstest.MapFile{Data: []byte(`INCLUDE "catch.vis"`)},
+		"lib/catch.vis": &fstest.MapFile{Data: []byte("CATCH foo 8 1\n")},
+	}
+	r, err := Includes(fsys, "root.vis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"lib/menu.vis", "lib/catch.vis", "flags.vis"}
+	if strings.Join(r, ",") != strings.Join(expect, ",") {
+		t.Fatalf("expected %v, got %v", expect, r)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	dir := t.TempDir()
+	src := map[string]string{
+		"root.vis": `INCLUDE "lib/flags.vis"
+LOAD foo 0
+
+CATCH nope suspended 1
+DOWN bar 0 go_bar
+UP 1 back
+MAP foo
+`,
+		"lib/flags.vis": `# flag names
+FLAG suspended 9
+MOVE top
+`,
+	}
+	for k, v := range src {
+		fp := path.Join(dir, k)
+		err := os.MkdirAll(path.Dir(fp), 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(fp, []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := Positions(path.Join(dir, "root.vis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := path.Join(dir, "root.vis")
+	lib := path.Join(dir, "lib/flags.vis")
+	expect := []Position{
+		{lib, 3},
+		{root, 2},
+		{root, 4},
+		{root, 5},
+		{root, 6},
+		{root, 6},
+		{root, 5},
+		{root, 6},
+		{root, 7},
+	}
+	if len(r) != len(expect) {
+		t.Fatalf("expected %v, got %v", expect, r)
+	}
+	for i, v := range expect {
+		if r[i] != v {
+			t.Fatalf("position %d: expected %v, got %v", i, v, r[i])
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"git.defalsify.org/vise.git/asm"
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/lint"
+	"git.defalsify.org/vise.git/resource"
+)
+
+// Config defines the resource directory to build and the checks to perform.
+type Config struct {
+	Dir string // Resource directory with assembly sources (<sym>.vis) and templates. Bytecode is written to the same directory.
+	Root string // Entry point for the linter. Defaults to "root".
+	Force bool // Assemble all sources, also if unchanged since the last build.
+	Container bool // Write bytecode in a container with versions, source hash and reference table.
+	NoLint bool // Do not run the linter.
+	Skip []string // Linter checks not to perform.
+}
+
+// FileError is an error in a single source or template file.
+type FileError struct {
+	File string
+	Err error
+}
+
+// Error implements the error interface.
+func(fe FileError) Error() string {
+	var errs asm.Errors
+	if errors.As(fe.Err, &errs) {
+		return fmt.Sprintf("%s:\n%v", fe.File, fe.Err)
+	}
+	return fmt.Sprintf("%s: %v", fe.File, fe.Err)
+}
+
+// Unwrap returns the error of the file.
+func(fe FileError) Unwrap() error {
+	return fe.Err
+}
+
+// Result is the outcome of a build.
+type Result struct {
+	Built []string // Source files assembled.
+	Unchanged []string // Source files skipped, as they have not changed since the last build.
+	Templates int // Number of templates validated.
+	Errors []FileError // Assembly and template errors.
+	Issues []lint.Issue // Linter issues.
+}
+
+// Failed returns true if the build had any errors or linter issues.
+func(r *Result) Failed() bool {
+	return len(r.Errors) > 0 || len(r.Issues) > 0
+}
+
+// String implements the String interface.
+//
+// Lists all errors and linter issues, followed by a summary line.
+func(r *Result) String() string {
+	var s []string
+	for _, v := range r.Errors {
+		s = append(s, v.Error())
+	}
+	for _, v := range r.Issues {
+		s = append(s, v.String())
+	}
+	status := "OK"
+	if r.Failed() {
+		status = "FAIL"
+	}
+	s = append(s, fmt.Sprintf("%s: %d built, %d unchanged, %d templates, %d errors, %d lint issues", status, len(r.Built), len(r.Unchanged), r.Templates, len(r.Errors), len(r.Issues)))
+	return strings.Join(s, "\n")
+}
+
+// Build assembles all changed sources in the resource directory, validates the templates of all nodes, and runs the linter.
+//
+// A source is changed if the hash of the source and the files it includes differs from the last build, or if its bytecode file is missing or not in the requested format. Source files producing no bytecode, such as files only holding macro or flag definitions, are not written.
+//
+// The linter is not run if there are any assembly errors.
+//
+// Errors in sources and templates are reported in the result. An error is returned only if the build could not be carried out.
+func Build(ctx context.Context, cfg Config) (*Result, error) {
+	r := &Result{}
+	if cfg.Root == "" {
+		cfg.Root = "root"
+	}
+	fsys := os.DirFS(cfg.Dir)
+	mf, err := readManifest(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	srcs, err := fs.Glob(fsys, "*.vis")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(srcs)
+
+	var nodes []string
+	for _, v := range srcs {
+		sym := strings.TrimSuffix(v, ".vis")
+		sh, err := asm.SourceHash(fsys, v)
+		if err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(sh[:])
+		fp := path.Join(cfg.Dir, sym + ".bin")
+		if !cfg.Force && mf[v] == h {
+			code, err := os.ReadFile(fp)
+			if err == nil && bytecode.IsContainer(code) == cfg.Container {
+				Logg.Debugf("unchanged", "file", v)
+				r.Unchanged = append(r.Unchanged, v)
+				nodes = append(nodes, sym)
+				continue
+			}
+		}
+		delete(mf, v)
+		b := bytes.NewBuffer(nil)
+		_, err = asm.ParseFS(fsys, v, b)
+		if err != nil {
+			r.Errors = append(r.Errors, FileError{File: v, Err: err})
+			continue
+		}
+		mf[v] = h
+		if b.Len() == 0 {
+			Logg.Debugf("no bytecode", "file", v)
+			continue
+		}
+		code := b.Bytes()
+		if cfg.Container {
+			code, err = asm.Container(code, sh, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = os.WriteFile(fp, code, 0644)
+		if err != nil {
+			return nil, err
+		}
+		Logg.Debugf("built", "file", v, "bytes", b.Len())
+		r.Built = append(r.Built, v)
+		nodes = append(nodes, sym)
+	}
+	err = mf.write(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkTemplates(fsys, nodes, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.NoLint || len(r.Errors) > 0 {
+		return r, nil
+	}
+	rs := resource.NewFsResource(cfg.Dir)
+	r.Issues, err = lint.Lint(ctx, lint.Config{
+		Root: cfg.Root,
+		SourceDir: cfg.Dir,
+		Skip: cfg.Skip,
+	}, rs)
+	if err != nil {
+		r.Errors = append(r.Errors, FileError{File: cfg.Root + ".bin", Err: err})
+	}
+	return r, nil
+}
+
+// parse the templates of all nodes, including translations and menu files.
+//
+// Templates are files named after the node, optionally followed by an underscore and a suffix, without extension.
+func checkTemplates(fsys fs.FS, nodes []string, r *Result) error {
+	seen := make(map[string]bool)
+	for _, sym := range nodes {
+		names, err := fs.Glob(fsys, sym + "_*")
+		if err != nil {
+			return err
+		}
+		names = append([]string{sym}, names...)
+		for _, v := range names {
+			if seen[v] || strings.Contains(v, ".") {
+				continue
+			}
+			seen[v] = true
+			st, err := fs.Stat(fsys, v)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return err
+			}
+			if st.IsDir() {
+				continue
+			}
+			b, err := fs.ReadFile(fsys, v)
+			if err != nil {
+				return err
+			}
+			r.Templates++
+			_, err = template.New(v).Option("missingkey=error").Parse(string(b))
+			if err != nil {
+				r.Errors = append(r.Errors, FileError{File: v, Err: err})
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package builder
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/lint"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for k, v := range files {
+		err := os.WriteFile(path.Join(dir, k), []byte(v), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"flags.vis": "FLAG done 8\n",
+		"root.vis": `INCLUDE "flags.vis"
+LOAD foo 0
+MAP foo
+CATCH bar done 1
+MOUT next 1
+HALT
+INCMP bar 1
+`,
+		"bar.vis": "HALT\n",
+		"root": "hello {{.foo}}",
+		"root_nor": "hei {{.foo}}",
+		"bar": "bye",
+		"foo.txt": "world",
+	})
+	cfg := Config{
+		Dir: dir,
+	}
+	r, err := Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Failed() {
+		t.Fatalf("build failed:\n%s", r)
+	}
+	if strings.Join(r.Built, ",") != "bar.vis,root.vis" {
+		t.Fatalf("unexpected built files: %v", r.Built)
+	}
+	if r.Templates != 3 {
+		t.Fatalf("expected 3 templates, got %d", r.Templates)
+	}
+	_, err = os.Stat(path.Join(dir, "flags.bin"))
+	if err == nil {
+		t.Fatalf("expected no bytecode for flags.vis")
+	}
+
+	r, err = Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Built) != 0 || len(r.Unchanged) != 2 {
+		t.Fatalf("expected no files built, got built %v, unchanged %v", r.Built, r.Unchanged)
+	}
+
+	writeFiles(t, dir, map[string]string{
+		"flags.vis": "FLAG done 9\n",
+	})
+	r, err = Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(r.Built, ",") != "root.vis" {
+		t.Fatalf("expected included change to rebuild root.vis, got %v", r.Built)
+	}
+
+	err = os.Remove(path.Join(dir, "bar.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Force = true
+	r, err = Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Built) != 2 {
+		t.Fatalf("expected all files built, got %v", r.Built)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root.vis": `MAP foo
+HALT
+`,
+		"broken.vis": `LOAD foo
+`,
+		"root": "hello {{.foo",
+	})
+	r, err := Build(ctx, Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Failed() {
+		t.Fatalf("expected build to fail")
+	}
+	if len(r.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got:\n%s", r)
+	}
+	if r.Errors[0].File != "broken.vis" || r.Errors[1].File != "root" {
+		t.Fatalf("unexpected errors:\n%s", r)
+	}
+	if len(r.Issues) != 0 {
+		t.Fatalf("expected linter not to run on assembly errors")
+	}
+
+	writeFiles(t, dir, map[string]string{
+		"broken.vis": "LOAD foo 0\n",
+		"root": "hello {{.foo}}",
+	})
+	r, err = Build(ctx, Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 0 {
+		t.Fatalf("expected no errors, got:\n%s", r)
+	}
+	if len(r.Issues) != 1 || r.Issues[0].Check != lint.CHECK_MAP_UNLOADED {
+		t.Fatalf("expected map-unloaded issue, got:\n%s", r)
+	}
+	s := r.String()
+	if !strings.Contains(s, "root.vis:1: ") || !strings.HasSuffix(s, "FAIL: 1 built, 1 unchanged, 1 templates, 0 errors, 1 lint issues") {
+		t.Fatalf("unexpected report:\n%s", s)
+	}
+}
+
+func TestBuildContainer(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root.vis": "LOAD foo 0\nMAP foo\nHALT\n",
+		"root": "hello {{.foo}}",
+		"foo.txt": "world",
+	})
+	cfg := Config{
+		Dir: dir,
+	}
+	r, err := Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Built) != 1 {
+		t.Fatalf("expected 1 file built, got %v", r.Built)
+	}
+
+	cfg.Container = true
+	r, err = Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Failed() {
+		t.Fatalf("build failed:\n%s", r)
+	}
+	if len(r.Built) != 1 {
+		t.Fatalf("expected format change to rebuild, got %v", r.Built)
+	}
+	b, err := os.ReadFile(path.Join(dir, "root.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _, err := bytecode.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == nil || h.Table == nil {
+		t.Fatalf("expected container with table")
+	}
+
+	r, err = Build(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Unchanged) != 1 {
+		t.Fatalf("expected container to be unchanged, got %v", r.Built)
+	}
+}
@@ -0,0 +1,2 @@
+// Package builder assembles all sources of a resource directory, validates the templates and runs the linter on the result.
+package builder
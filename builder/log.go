@@ -0,0 +1,13 @@
+package builder
+
+import (
+	"git.defalsify.org/vise.git/logging"
+)
+
+var (
+	Logg logging.Logger = logging.NewVanilla().WithDomain("builder")
+)
+
+func init() {
+	logging.Register("builder", &Logg)
+}
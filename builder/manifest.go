@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// File in the resource directory holding the source hashes of the last build.
+	ManifestFile = ".vise.sum"
+)
+
+// source hashes of assembled files, by source file name.
+type manifest map[string]string
+
+// read the manifest from the directory.
+//
+// A missing manifest is returned empty.
+func readManifest(dir string) (manifest, error) {
+	mf := make(manifest)
+	f, err := os.Open(path.Join(dir, ManifestFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mf, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		v := strings.Fields(sc.Text())
+		if len(v) != 2 {
+			continue
+		}
+		mf[v[1]] = v[0]
+	}
+	return mf, sc.Err()
+}
+
+// write the manifest to the directory, in the format of sha256sum.
+func(mf manifest) write(dir string) error {
+	var names []string
+	for k := range mf {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, v := range names {
+		fmt.Fprintf(&b, "%s  %s\n", mf[v], v)
+	}
+	return os.WriteFile(path.Join(dir, ManifestFile), []byte(b.String()), 0644)
+}
@@ -0,0 +1,199 @@
+package bytecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// Identifies bytecode in a container.
+	//
+	// Raw instructions always start with a zero byte, as all opcodes are smaller than 256.
+	MAGIC = "VISE"
+	// Version of the vm instruction set. Bytecode assembled for a different vm version is rejected.
+	//
+	// Version 1 added MAXAGE.
+	VM_VERSION = 1
+	// Size of the source hash in the header.
+	HASH_SIZE = 32
+	// Size of the fixed part of the header: magic, vm version, assembler version, source hash and table length.
+	HEADER_SIZE = len(MAGIC) + 2 + 2 + HASH_SIZE + 4
+)
+
+// VersionError indicates bytecode assembled for a different vm version.
+type VersionError struct {
+	Version uint16 // The vm version of the bytecode.
+}
+
+// Error implements the error interface.
+func(e VersionError) Error() string {
+	return fmt.Sprintf("bytecode is for vm version %d, but this vm is version %d; the source must be assembled again", e.Version, VM_VERSION)
+}
+
+// Table lists the symbols, flags and selectors referenced by the instructions in a container.
+//
+// All entries are unique, in order of first reference.
+type Table struct {
+	Symbols []string // External code symbols and nodes.
+	Flags []uint32 // Signal flags of CATCH and CROAK.
+	Selectors []string // Selectors of menu items and input matches.
+}
+
+// Header holds the metadata of bytecode in a container.
+type Header struct {
+	VmVersion uint16
+	AsmVersion uint16
+	SourceHash [HASH_SIZE]byte // sha256 of the assembly source.
+	Table *Table // Optional table of references.
+}
+
+// IsContainer returns true if the bytecode starts with a container header.
+func IsContainer(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(MAGIC))
+}
+
+// Encode returns the instructions in a container with the given header.
+func Encode(h Header, code []byte) []byte {
+	var tb []byte
+	if h.Table != nil {
+		tb = h.Table.encode()
+	}
+	b := bytes.NewBuffer(nil)
+	b.WriteString(MAGIC)
+	binary.Write(b, binary.BigEndian, h.VmVersion)
+	binary.Write(b, binary.BigEndian, h.AsmVersion)
+	b.Write(h.SourceHash[:])
+	binary.Write(b, binary.BigEndian, uint32(len(tb)))
+	b.Write(tb)
+	b.Write(code)
+	return b.Bytes()
+}
+
+// Decode splits bytecode in a container into header and instructions.
+//
+// Bytecode without container header is returned unchanged, with a nil header.
+//
+// Versions are not checked. Use Code to also reject incompatible bytecode.
+func Decode(b []byte) (*Header, []byte, error) {
+	if !IsContainer(b) {
+		return nil, b, nil
+	}
+	if len(b) < HEADER_SIZE {
+		return nil, nil, fmt.Errorf("bytecode header too short: %d bytes", len(b))
+	}
+	h := &Header{}
+	c := len(MAGIC)
+	h.VmVersion = binary.BigEndian.Uint16(b[c:])
+	c += 2
+	h.AsmVersion = binary.BigEndian.Uint16(b[c:])
+	c += 2
+	copy(h.SourceHash[:], b[c:c+HASH_SIZE])
+	c += HASH_SIZE
+	l := int(binary.BigEndian.Uint32(b[c:]))
+	c += 4
+	if len(b) - c < l {
+		return nil, nil, fmt.Errorf("bytecode symbol table truncated: need %d bytes, have %d", l, len(b) - c)
+	}
+	if l > 0 {
+		tb, err := decodeTable(b[c:c+l])
+		if err != nil {
+			return nil, nil, err
+		}
+		h.Table = tb
+	}
+	c += l
+	return h, b[c:], nil
+}
+
+// Code returns the instructions of the bytecode, with the container header removed if present.
+//
+// Fails with VersionError if the bytecode was assembled for a different vm version.
+func Code(b []byte) ([]byte, error) {
+	h, code, err := Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if h != nil && h.VmVersion != VM_VERSION {
+		return nil, VersionError{Version: h.VmVersion}
+	}
+	return code, nil
+}
+
+// serialize the table.
+//
+// Each list is prefixed with its number of entries as uint16. Strings are prefixed with their length as a single byte, and flags are written as uint32.
+func(tb *Table) encode() []byte {
+	b := bytes.NewBuffer(nil)
+	writeStrings(b, tb.Symbols)
+	binary.Write(b, binary.BigEndian, uint16(len(tb.Flags)))
+	for _, v := range tb.Flags {
+		binary.Write(b, binary.BigEndian, v)
+	}
+	writeStrings(b, tb.Selectors)
+	return b.Bytes()
+}
+
+func writeStrings(b *bytes.Buffer, s []string) {
+	binary.Write(b, binary.BigEndian, uint16(len(s)))
+	for _, v := range s {
+		b.WriteByte(uint8(len(v)))
+		b.WriteString(v)
+	}
+}
+
+// deserialize the table.
+func decodeTable(b []byte) (*Table, error) {
+	var err error
+	tb := &Table{}
+	r := bytes.NewReader(b)
+	tb.Symbols, err = readStrings(r)
+	if err != nil {
+		return nil, err
+	}
+	var c uint16
+	err = binary.Read(r, binary.BigEndian, &c)
+	if err != nil {
+		return nil, errTable(err)
+	}
+	tb.Flags = make([]uint32, c)
+	err = binary.Read(r, binary.BigEndian, tb.Flags)
+	if err != nil {
+		return nil, errTable(err)
+	}
+	tb.Selectors, err = readStrings(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("bytecode symbol table has %d trailing bytes", r.Len())
+	}
+	return tb, nil
+}
+
+func readStrings(r *bytes.Reader) ([]string, error) {
+	var c uint16
+	err := binary.Read(r, binary.BigEndian, &c)
+	if err != nil {
+		return nil, errTable(err)
+	}
+	s := make([]string, c)
+	for i := range s {
+		l, err := r.ReadByte()
+		if err != nil {
+			return nil, errTable(err)
+		}
+		v := make([]byte, l)
+		_, err = io.ReadFull(r, v)
+		if err != nil {
+			return nil, errTable(err)
+		}
+		s[i] = string(v)
+	}
+	return s, nil
+}
+
+func errTable(err error) error {
+	return fmt.Errorf("bytecode symbol table invalid: %v", err)
+}
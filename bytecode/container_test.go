@@ -0,0 +1,101 @@
+package bytecode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testCode = []byte{0x00, 0x07}
+
+func TestEncodeDecode(t *testing.T) {
+	h := Header{
+		VmVersion: VM_VERSION,
+		AsmVersion: 1,
+		Table: &Table{
+			Symbols: []string{"foo", "bar"},
+			Flags: []uint32{8, 666},
+			Selectors: []string{"1", "*"},
+		},
+	}
+	h.SourceHash[0] = 0x2a
+	b := Encode(h, testCode)
+	if !IsContainer(b) {
+		t.Fatalf("expected container")
+	}
+	hh, code, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*hh, h) {
+		t.Fatalf("expected %v, got %v", h, hh)
+	}
+	if !bytes.Equal(code, testCode) {
+		t.Fatalf("expected code %x, got %x", testCode, code)
+	}
+
+	h.Table = nil
+	b = Encode(h, testCode)
+	hh, code, err = Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hh.Table != nil {
+		t.Fatalf("expected no table")
+	}
+	if len(b) != HEADER_SIZE + len(testCode) {
+		t.Fatalf("expected %d bytes, got %d", HEADER_SIZE + len(testCode), len(b))
+	}
+}
+
+func TestCodeLegacy(t *testing.T) {
+	if IsContainer(testCode) {
+		t.Fatalf("expected raw code")
+	}
+	code, err := Code(testCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, testCode) {
+		t.Fatalf("expected code %x, got %x", testCode, code)
+	}
+	code, err = Code(Encode(Header{VmVersion: VM_VERSION}, testCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, testCode) {
+		t.Fatalf("expected code %x, got %x", testCode, code)
+	}
+}
+
+func TestCodeVersion(t *testing.T) {
+	b := Encode(Header{VmVersion: VM_VERSION + 1}, testCode)
+	_, err := Code(b)
+	var verr VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+	if verr.Version != VM_VERSION + 1 {
+		t.Fatalf("expected version %d, got %d", VM_VERSION + 1, verr.Version)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	h := Header{
+		Table: &Table{
+			Symbols: []string{"foo"},
+		},
+	}
+	b := Encode(h, nil)
+	for _, v := range [][]byte{
+		b[:HEADER_SIZE - 1],
+		b[:len(b) - 1],
+		[]byte(MAGIC),
+	} {
+		_, _, err := Decode(v)
+		if err == nil {
+			t.Fatalf("expected error for %x", v)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+// Package bytecode defines the container format for assembled vise bytecode.
+//
+// A container prefixes the instructions with a header holding the vm and assembler versions, a hash of the assembly source and an optional table of the symbols, flags and selectors referenced by the instructions.
+//
+// Bytecode without a container header is still accepted, and is assumed to be compatible.
+package bytecode
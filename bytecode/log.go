@@ -0,0 +1,13 @@
+package bytecode
+
+import (
+	"git.defalsify.org/vise.git/logging"
+)
+
+var (
+	Logg logging.Logger = logging.NewVanilla().WithDomain("bytecode")
+)
+
+func init() {
+	logging.Register("bytecode", &Logg)
+}
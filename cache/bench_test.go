@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+// cache with the given number of frames, each holding the given number of loaded symbols.
+func newBenchCache(b *testing.B, frames int, keys int) *Cache {
+	ca := NewCache()
+	for i := 0; i < frames; i++ {
+		ca.Push()
+		for j := 0; j < keys; j++ {
+			err := ca.Add(fmt.Sprintf("sym_%d_%d", i, j), "value", 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return ca
+}
+
+func benchmarkGet(b *testing.B, frames int, keys int) {
+	ca := newBenchCache(b, frames, keys)
+	key := fmt.Sprintf("sym_%d_%d", frames - 1, keys - 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ca.Get(key)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkUpdate(b *testing.B, frames int, keys int) {
+	ca := newBenchCache(b, frames, keys)
+	key := fmt.Sprintf("sym_%d_%d", 0, keys - 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := ca.Update(key, "other")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// descend into and ascend from a node loading symbols, on top of a deep menu.
+func benchmarkPushAddPop(b *testing.B, frames int, keys int) {
+	ca := newBenchCache(b, frames, keys)
+	var syms []string
+	for j := 0; j < keys; j++ {
+		syms = append(syms, fmt.Sprintf("node_%d", j))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ca.Push()
+		for _, k := range syms {
+			err := ca.Add(k, "value", 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		ca.Pop()
+	}
+}
+
+func BenchmarkGetShallow(b *testing.B) {
+	benchmarkGet(b, 2, 4)
+}
+
+func BenchmarkGetDeep(b *testing.B) {
+	benchmarkGet(b, 16, 32)
+}
+
+func BenchmarkUpdateDeep(b *testing.B) {
+	benchmarkUpdate(b, 16, 32)
+}
+
+func BenchmarkPushAddPopShallow(b *testing.B) {
+	benchmarkPushAddPop(b, 2, 4)
+}
+
+func BenchmarkPushAddPopDeep(b *testing.B) {
+	benchmarkPushAddPop(b, 16, 32)
+}
@@ -2,14 +2,25 @@ package cache
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 // Cache stores loaded content, enforcing size limits and keeping track of size usage.
+//
+// Symbols are looked up through an index of the frame they were loaded in. The frames must therefore only be changed through the methods of the Cache.
 type Cache struct {
 	CacheSize uint32 // Total allowed cumulative size of values (not code) in cache
 	CacheUseSize uint32 // Currently used bytes by all values (not code) in cache
 	Cache []map[string]string // All loaded cache items
 	Sizes map[string]uint16 // Size limits for all loaded symbols.
+	Times map[string]int64 // Time all loaded symbols were last loaded or updated, in unix nanoseconds.
+	MaxAges map[string]uint32 // Maximum age in seconds for loaded symbols, if set.
+	SharedSizes map[string]uint32 // Sizes of loaded symbols whose value is held in the shared store instead of the frame.
+	shared *SharedStore
+	now func() time.Time
+	index map[string]int // frame of each loaded symbol, built on first use.
 }
 
 // NewCache creates a new ready-to-use cache object
@@ -17,6 +28,9 @@ func NewCache() *Cache {
 	ca := &Cache{
 		Cache: []map[string]string{make(map[string]string)},
 		Sizes: make(map[string]uint16),
+		Times: make(map[string]int64),
+		MaxAges: make(map[string]uint32),
+		SharedSizes: make(map[string]uint32),
 	}
 	return ca
 }
@@ -27,10 +41,36 @@ func(ca *Cache) WithCacheSize(cacheSize uint32) *Cache {
 	return ca
 }
 
+// WithSharedStore sets the store to serve shared symbols from, instead of the process-wide Shared store.
+func(ca *Cache) WithSharedStore(ss *SharedStore) *Cache {
+	ca.shared = ss
+	return ca
+}
+
+// GetShared implements SharedMemory interface.
+func(ca *Cache) GetShared(key string) (string, bool) {
+	return ca.sharedStore().Get(key)
+}
+
+// PutShared implements SharedMemory interface.
+func(ca *Cache) PutShared(key string, val string) bool {
+	return ca.sharedStore().Put(key, val)
+}
+
+// the store of shared symbols in use.
+func(ca *Cache) sharedStore() *SharedStore {
+	if ca.shared == nil {
+		return Shared
+	}
+	return ca.shared
+}
+
 // Add adds a cache value under a cache symbol key.
 //
 // Also stores the size limitation of for key for later updates.
 //
+// If the key is a shared symbol and the shared store holds the same value, only the size of the value is kept in the frame. The size still counts against the cumulative cache capacity.
+//
 // Fails if:
 // - key already defined
 // - value is longer than size limit
@@ -60,9 +100,12 @@ func(ca *Cache) Add(key string, value string, sizeLimit uint16) error {
 	}
 	Logg.Infof("Cache add", "key", key, "size", sz, "limit", sizeLimit)
 	Logg.Tracef("", "Cache add data", value)
-	ca.Cache[len(ca.Cache)-1][key] = value
+	ca.set(len(ca.Cache) - 1, key, value)
+	ca.indexOf()[key] = len(ca.Cache) - 1
 	ca.CacheUseSize += sz
 	ca.Sizes[key] = sizeLimit
+	ca.touch(key)
+	delete(ca.MaxAges, key)
 	return nil
 }
 
@@ -95,25 +138,23 @@ func(ca *Cache) Update(key string, value string) error {
 	if checkFrame == -1 {
 		return fmt.Errorf("key %v not defined", key)
 	}
-	r := ca.Cache[checkFrame][key]
-	l := uint32(len(r))
-	ca.Cache[checkFrame][key] = ""
+	l := ca.sizeOf(key, ca.Cache[checkFrame][key])
 	ca.CacheUseSize -= l
 	sz := ca.checkCapacity(value)
 	if sz == 0 {
 		baseUseSize := ca.CacheUseSize
-		ca.Cache[checkFrame][key] = r
 		ca.CacheUseSize += l
 		return fmt.Errorf("Cache capacity exceeded %v of %v", baseUseSize + sz, ca.CacheSize)
 	}
-	ca.Cache[checkFrame][key] = value
+	ca.set(checkFrame, key, value)
 	ca.CacheUseSize += uint32(len(value))
+	ca.touch(key)
 	return nil
 }
 
 // Get the content currently loaded for a single key, loaded at any level.
 //
-// Fails if key has not been loaded.
+// Fails if key has not been loaded. Fails with SharedExpiredError if the value is held in the shared store, and the store no longer has a value of the same size.
 func(ca *Cache) Get(key string) (string, error) {
 	i := ca.frameOf(key)
 	if i == -1 {
@@ -123,6 +164,14 @@ func(ca *Cache) Get(key string) (string, error) {
 	if !ok {
 		return "", fmt.Errorf("unknown key '%s'", key)
 	}
+	sz, ok := ca.SharedSizes[key]
+	if !ok {
+		return r, nil
+	}
+	r, ok = ca.sharedStore().Get(key)
+	if !ok || uint32(len(r)) != sz {
+		return "", &SharedExpiredError{Key: key}
+	}
 	return r, nil
 }
 
@@ -131,6 +180,10 @@ func(ca *Cache) Reset() {
 	if len(ca.Cache) == 0 {
 		return
 	}
+	for _, m := range ca.Cache[1:] {
+		ca.forget(m)
+		ca.unindex(m)
+	}
 	ca.Cache = ca.Cache[:1]
 	ca.CacheUseSize = 0
 	return
@@ -154,10 +207,12 @@ func (ca *Cache) Pop() error {
 	l -= 1
 	m := ca.Cache[l]
 	for k, v := range m {
-		sz := len(v)
-		ca.CacheUseSize -= uint32(sz)
+		sz := ca.sizeOf(k, v)
+		ca.CacheUseSize -= sz
 		Logg.Debugf("Cache free", "frame", l, "key", k, "size", sz)
 	}
+	ca.forget(m)
+	ca.unindex(m)
 	ca.Cache = ca.Cache[:l]
 	//ca.resetCurrent()
 	return nil
@@ -165,7 +220,98 @@ func (ca *Cache) Pop() error {
 
 // Check returns true if a key already exists in the cache.
 func(ca *Cache) Check(key string) bool {
-	return ca.frameOf(key) == -1
+	return ca.frameOf(key) != -1
+}
+
+// Age returns the time since the value for the key was loaded or last updated.
+//
+// Fails if the key is not loaded, or if the load time is not known.
+func(ca *Cache) Age(key string) (time.Duration, error) {
+	if ca.frameOf(key) == -1 {
+		return 0, fmt.Errorf("key %v not defined", key)
+	}
+	t, ok := ca.Times[key]
+	if !ok {
+		return 0, fmt.Errorf("no load time for key %v", key)
+	}
+	return ca.clock().Sub(time.Unix(0, t)), nil
+}
+
+// SetMaxAge sets the maximum age of the value for the key, after which it should be loaded again.
+//
+// The maximum age is kept until the key is freed or loaded again. It is stored with a resolution of seconds.
+//
+// Fails if the key is not loaded.
+func(ca *Cache) SetMaxAge(key string, maxAge time.Duration) error {
+	if ca.frameOf(key) == -1 {
+		return fmt.Errorf("key %v not defined", key)
+	}
+	if ca.MaxAges == nil {
+		ca.MaxAges = make(map[string]uint32)
+	}
+	ca.MaxAges[key] = uint32(maxAge / time.Second)
+	return nil
+}
+
+// MaxAge returns the maximum age set for the key with SetMaxAge.
+func(ca *Cache) MaxAge(key string) (time.Duration, bool) {
+	v, ok := ca.MaxAges[key]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(v) * time.Second, true
+}
+
+// record the current time as load time of the key.
+func(ca *Cache) touch(key string) {
+	if ca.Times == nil {
+		ca.Times = make(map[string]int64)
+	}
+	ca.Times[key] = ca.clock().UnixNano()
+}
+
+// remove the load times, maximum ages and shared sizes of all keys in the frame.
+func(ca *Cache) forget(m map[string]string) {
+	for k := range m {
+		delete(ca.Times, k)
+		delete(ca.MaxAges, k)
+		delete(ca.SharedSizes, k)
+	}
+}
+
+// store the value for the key in the frame.
+//
+// If the shared store holds the same value for the key, only its size is kept.
+func(ca *Cache) set(frame int, key string, value string) {
+	v, ok := ca.sharedStore().Get(key)
+	if !ok || v != value {
+		ca.Cache[frame][key] = value
+		delete(ca.SharedSizes, key)
+		return
+	}
+	if ca.SharedSizes == nil {
+		ca.SharedSizes = make(map[string]uint32)
+	}
+	ca.Cache[frame][key] = ""
+	ca.SharedSizes[key] = uint32(len(value))
+	Logg.Debugf("Cache add shared reference", "key", key, "size", len(value))
+}
+
+// bytes used by the value of the key, given the value stored in its frame.
+func(ca *Cache) sizeOf(key string, v string) uint32 {
+	sz, ok := ca.SharedSizes[key]
+	if ok {
+		return sz
+	}
+	return uint32(len(v))
+}
+
+// current time.
+func(ca *Cache) clock() time.Time {
+	if ca.now == nil {
+		return time.Now()
+	}
+	return ca.now()
 }
 
 // bytes that will be added to cache use size for string
@@ -181,14 +327,47 @@ func(ca *Cache) checkCapacity(v string) uint32 {
 	return sz
 }
 
+// UnmarshalCBOR implements cbor.Unmarshaler.
+//
+// The symbol index is rebuilt, as the deserialized frames replace the current ones.
+func(ca *Cache) UnmarshalCBOR(b []byte) error {
+	type plain Cache
+	err := cbor.Unmarshal(b, (*plain)(ca))
+	ca.index = nil
+	ca.indexOf()
+	return err
+}
+
 // return 0-indexed frame number where key is defined. -1 if not defined
 func(ca *Cache) frameOf(key string) int {
+	i, ok := ca.indexOf()[key]
+	if ok && i >= len(ca.Cache) {
+		ca.index = nil
+		i, ok = ca.indexOf()[key]
+	}
+	if !ok {
+		return -1
+	}
+	return i
+}
+
+// the frame index of all loaded symbols, which is built from the frames if not available.
+func(ca *Cache) indexOf() map[string]int {
+	if ca.index != nil {
+		return ca.index
+	}
+	ca.index = make(map[string]int)
 	for i, m := range ca.Cache {
-		for k, _ := range m {
-			if k == key {
-				return i
-			}
+		for k := range m {
+			ca.index[k] = i
 		}
 	}
-	return -1
+	return ca.index
+}
+
+// remove all keys in the frame from the frame index.
+func(ca *Cache) unindex(m map[string]string) {
+	for k := range m {
+		delete(ca.index, k)
+	}
 }
@@ -2,6 +2,9 @@ package cache
 
 import (
 	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 func TestNewCache(t *testing.T) {
@@ -102,3 +105,194 @@ func TestCacheLoadDup(t *testing.T) {
 	}
 }
 
+
+func TestCacheAge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ca := NewCache()
+	ca.now = func() time.Time {
+		return now
+	}
+	ca.Push()
+	err := ca.Add("foo", "bar", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ca.Age("baz")
+	if err == nil {
+		t.Fatalf("expected error on unknown key")
+	}
+	err = ca.SetMaxAge("baz", time.Minute)
+	if err == nil {
+		t.Fatalf("expected error on unknown key")
+	}
+
+	now = now.Add(time.Minute)
+	age, err := ca.Age("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if age != time.Minute {
+		t.Fatalf("expected age %v, got %v", time.Minute, age)
+	}
+	err = ca.SetMaxAge("foo", 30 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxAge, ok := ca.MaxAge("foo")
+	if !ok || maxAge != 30 * time.Second {
+		t.Fatalf("expected max age 30s, got %v", maxAge)
+	}
+
+	err = ca.Update("foo", "xyzzy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	age, _ = ca.Age("foo")
+	if age != 0 {
+		t.Fatalf("expected age reset on update, got %v", age)
+	}
+	_, ok = ca.MaxAge("foo")
+	if !ok {
+		t.Fatalf("expected max age kept on update")
+	}
+
+	ca.Pop()
+	_, ok = ca.MaxAge("foo")
+	if ok || len(ca.Times) > 0 {
+		t.Fatalf("expected load time and max age freed with frame")
+	}
+}
+
+func TestCacheIndex(t *testing.T) {
+	ca := NewCache()
+	ca.Add("foo", "bar", 0)
+	ca.Push()
+	ca.Add("baz", "xyzzy", 0)
+	ca.Push()
+	ca.Add("inky", "pinky", 0)
+	ca.Pop()
+	_, err := ca.Get("inky")
+	if err == nil {
+		t.Fatalf("expected popped key to be gone")
+	}
+	err = ca.Add("inky", "blinky", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca.frameOf("inky") != 1 || ca.frameOf("foo") != 0 {
+		t.Fatalf("unexpected frames: %v", ca.index)
+	}
+
+	b, err := cbor.Marshal(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := NewCache()
+	cb.Add("clyde", "sue", 0)
+	err = cbor.Unmarshal(b, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := cb.Get("inky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "blinky" {
+		t.Fatalf("expected 'blinky', got '%s'", r)
+	}
+	_, err = cb.Get("clyde")
+	if err == nil {
+		t.Fatalf("expected replaced key to be gone")
+	}
+
+	ca.Reset()
+	_, err = ca.Get("baz")
+	if err == nil {
+		t.Fatalf("expected reset key to be gone")
+	}
+	_, err = ca.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check used to return true for keys that were not loaded.
+func TestCacheCheck(t *testing.T) {
+	ca := NewCache()
+	if ca.Check("foo") {
+		t.Fatalf("expected foo not to exist")
+	}
+	ca.Add("baz", "xyzzy", 0)
+	ca.Push()
+	ca.Add("foo", "bar", 0)
+	if !ca.Check("foo") {
+		t.Fatalf("expected foo to exist")
+	}
+	if !ca.Check("baz") {
+		t.Fatalf("expected baz in lower frame to exist")
+	}
+	err := ca.Update("foo", "inky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ca.Check("foo") {
+		t.Fatalf("expected updated foo to exist")
+	}
+	ca.Pop()
+	if ca.Check("foo") {
+		t.Fatalf("expected foo freed")
+	}
+	ca.Push()
+	ca.Add("foo", "bar", 0)
+	ca.Reset()
+	if ca.Check("foo") {
+		t.Fatalf("expected foo freed on reset")
+	}
+	if !ca.Check("baz") {
+		t.Fatalf("expected baz in top frame to remain after reset")
+	}
+}
+
+func TestCacheInspect(t *testing.T) {
+	ca := NewCache().WithCacheSize(20)
+	ca.Add("foo", "bar", 5)
+	ca.Push()
+	ca.Add("inky", "pinky", 0)
+	ca.Add("blinky", "clyde", 10)
+
+	u := ca.Inspect()
+	if u.Size != 13 || u.Limit != 20 {
+		t.Fatalf("unexpected usage: %v", u)
+	}
+	if len(u.Frames) != 2 || u.Frames[0].Size != 3 || u.Frames[1].Size != 10 {
+		t.Fatalf("unexpected frames: %v", u.Frames)
+	}
+	e := u.Frames[1].Entries
+	if len(e) != 2 || e[0].Key != "blinky" || e[0].Limit != 10 || e[1].Key != "inky" || e[1].Size != 5 {
+		t.Fatalf("unexpected entries: %v", e)
+	}
+	r, ok := u.Remaining()
+	if !ok || r != 7 {
+		t.Fatalf("expected 7 bytes remaining, got %v", r)
+	}
+	expect := `cache: 13 of 20 bytes used
+frame 0: 3 bytes
+	foo: 3 bytes, limit 5
+frame 1: 10 bytes
+	blinky: 5 bytes, limit 10
+	inky: 5 bytes, limit 0
+`
+	if u.String() != expect {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expect, u)
+	}
+
+	ca.Pop()
+	u = ca.Inspect()
+	if len(u.Frames) != 1 || u.Size != 3 {
+		t.Fatalf("unexpected usage after pop: %v", u)
+	}
+	_, ok = NewCache().Inspect().Remaining()
+	if ok {
+		t.Fatalf("expected no limit")
+	}
+}
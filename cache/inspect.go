@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Entry describes the cache use of a single loaded symbol.
+type Entry struct {
+	Key string `json:"key"`
+	Size uint32 `json:"size"` // Bytes used by the value.
+	Limit uint16 `json:"limit"` // Size limit given when the symbol was loaded. Zero for sink symbols.
+	Shared bool `json:"shared,omitempty"` // Value is held in the shared store.
+}
+
+// Frame describes the symbols loaded in a single cache frame.
+type Frame struct {
+	Entries []Entry `json:"entries"` // Loaded symbols, ordered by key.
+	Size uint32 `json:"size"` // Bytes used by all values in the frame.
+}
+
+// Usage is a read-only snapshot of the contents and size usage of a cache.
+type Usage struct {
+	Frames []Frame `json:"frames"` // All frames, from the top level down.
+	Size uint32 `json:"size"` // Bytes counted against the size limit.
+	Limit uint32 `json:"limit"` // Cumulative size limit of all values. Zero if there is no limit.
+}
+
+// Remaining returns the number of bytes still available, and false if there is no size limit.
+func(u Usage) Remaining() (uint32, bool) {
+	if u.Limit == 0 {
+		return 0, false
+	}
+	if u.Size > u.Limit {
+		return 0, true
+	}
+	return u.Limit - u.Size, true
+}
+
+// String implements the String interface.
+//
+// One line is written for the total usage, followed by one line for each frame and each loaded symbol.
+func(u Usage) String() string {
+	var b strings.Builder
+	if u.Limit == 0 {
+		fmt.Fprintf(&b, "cache: %d bytes used, no limit\n", u.Size)
+	} else {
+		fmt.Fprintf(&b, "cache: %d of %d bytes used\n", u.Size, u.Limit)
+	}
+	for i, v := range u.Frames {
+		fmt.Fprintf(&b, "frame %d: %d bytes\n", i, v.Size)
+		for _, e := range v.Entries {
+			fmt.Fprintf(&b, "\t%s: %d bytes, limit %d\n", e.Key, e.Size, e.Limit)
+		}
+	}
+	return b.String()
+}
+
+// Inspect implements Memory interface.
+func(ca *Cache) Inspect() Usage {
+	u := Usage{
+		Size: ca.CacheUseSize,
+		Limit: ca.CacheSize,
+	}
+	for _, m := range ca.Cache {
+		var fr Frame
+		for k, v := range m {
+			_, shared := ca.SharedSizes[k]
+			e := Entry{
+				Key: k,
+				Size: ca.sizeOf(k, v),
+				Limit: ca.Sizes[k],
+				Shared: shared,
+			}
+			fr.Entries = append(fr.Entries, e)
+			fr.Size += e.Size
+		}
+		sort.Slice(fr.Entries, func(i int, j int) bool {
+			return fr.Entries[i].Key < fr.Entries[j].Key
+		})
+		u.Frames = append(u.Frames, fr)
+	}
+	return u
+}
@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("cache")
 )
+
+func init() {
+	logging.Register("cache", &Logg)
+}
@@ -1,5 +1,9 @@
 package cache
 
+import (
+	"time"
+)
+
 // Memory defines the interface for store of a symbol mapped content store.
 type Memory interface {
 	Add(key string, val string, sizeLimit uint16) error
@@ -10,3 +14,15 @@ type Memory interface {
 	Pop() error
 	Reset()
 }
+
+// InspectableMemory is implemented by stores that can report the loaded symbols and their size usage.
+type InspectableMemory interface {
+	Inspect() Usage // Get a snapshot of the loaded symbols and size usage.
+}
+
+// AgedMemory is implemented by stores that keep track of when values were loaded.
+type AgedMemory interface {
+	Age(key string) (time.Duration, error) // Time since the value was loaded or last updated.
+	MaxAge(key string) (time.Duration, bool) // Maximum age set for the value, if any.
+	SetMaxAge(key string, maxAge time.Duration) error // Set the maximum age for a loaded value.
+}
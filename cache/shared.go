@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Shared is the process-wide store of shared symbols used by all Cache objects that have no other store set with WithSharedStore.
+var Shared = NewSharedStore()
+
+// SharedMemory is implemented by stores able to serve symbols shared between sessions.
+type SharedMemory interface {
+	GetShared(key string) (string, bool) // Get the value of a shared symbol, if it is declared and still valid.
+	PutShared(key string, val string) bool // Store the value of a shared symbol. Returns false if the symbol is not declared as shared.
+}
+
+// SharedExpiredError is returned when the value of a loaded shared symbol is no longer available from the shared store.
+//
+// The symbol should be loaded again.
+type SharedExpiredError struct {
+	Key string
+}
+
+// Error implements the error interface.
+func(e *SharedExpiredError) Error() string {
+	return fmt.Sprintf("shared value for key '%s' expired", e.Key)
+}
+
+// value of a shared symbol with its expiry time.
+type sharedEntry struct {
+	value string
+	expires time.Time
+}
+
+// SharedStore holds the values of symbols shared between sessions, such as product lists or exchange rates.
+//
+// Each symbol is declared with a time to live. Values are only served until they expire, and are removed from the store when they are next retrieved or when Purge is called.
+//
+// It is safe for concurrent use.
+type SharedStore struct {
+	mu sync.RWMutex
+	ttl map[string]time.Duration
+	entries map[string]sharedEntry
+	now func() time.Time
+}
+
+// NewSharedStore creates a new, empty SharedStore.
+func NewSharedStore() *SharedStore {
+	return &SharedStore{
+		ttl: make(map[string]time.Duration),
+		entries: make(map[string]sharedEntry),
+		now: time.Now,
+	}
+}
+
+// Declare makes the symbol shared between sessions, with values valid for the given duration.
+//
+// Values are keyed by symbol only, so the content of a shared symbol must not depend on the language or any other state of the session.
+//
+// A value already stored for the symbol is dropped.
+func(ss *SharedStore) Declare(key string, ttl time.Duration) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.ttl[key] = ttl
+	delete(ss.entries, key)
+}
+
+// IsShared returns true if the symbol has been declared as shared.
+func(ss *SharedStore) IsShared(key string) bool {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	_, ok := ss.ttl[key]
+	return ok
+}
+
+// Get returns the value of the shared symbol, if one is stored and has not expired.
+func(ss *SharedStore) Get(key string) (string, bool) {
+	ss.mu.RLock()
+	v, ok := ss.entries[key]
+	ss.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	if !ss.now().Before(v.expires) {
+		ss.mu.Lock()
+		w, ok := ss.entries[key]
+		if ok && w.expires == v.expires {
+			delete(ss.entries, key)
+		}
+		ss.mu.Unlock()
+		Logg.Debugf("shared value expired", "key", key)
+		return "", false
+	}
+	return v.value, true
+}
+
+// Put stores the value for the shared symbol, valid for the time to live of the symbol.
+//
+// Returns false, and stores nothing, if the symbol has not been declared as shared.
+func(ss *SharedStore) Put(key string, val string) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ttl, ok := ss.ttl[key]
+	if !ok {
+		return false
+	}
+	ss.entries[key] = sharedEntry{
+		value: val,
+		expires: ss.now().Add(ttl),
+	}
+	Logg.Debugf("shared value stored", "key", key, "size", len(val), "ttl", ttl)
+	return true
+}
+
+// Expire removes the stored value of the shared symbol, so that it is retrieved again on next use.
+func(ss *SharedStore) Expire(key string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.entries, key)
+}
+
+// Purge removes all expired values from the store.
+func(ss *SharedStore) Purge() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	now := ss.now()
+	for k, v := range ss.entries {
+		if !now.Before(v.expires) {
+			delete(ss.entries, k)
+		}
+	}
+}
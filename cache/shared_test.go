@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSharedStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ss := NewSharedStore()
+	ss.now = func() time.Time {
+		return now
+	}
+	if ss.Put("foo", "bar") {
+		t.Fatalf("expected undeclared symbol to be refused")
+	}
+	ss.Declare("foo", time.Minute)
+	ss.Declare("baz", time.Hour)
+	if !ss.IsShared("foo") || ss.IsShared("bar") {
+		t.Fatalf("unexpected shared symbols")
+	}
+	if !ss.Put("foo", "bar") || !ss.Put("baz", "xyzzy") {
+		t.Fatalf("expected declared symbols to be stored")
+	}
+	r, ok := ss.Get("foo")
+	if !ok || r != "bar" {
+		t.Fatalf("expected 'bar', got '%s'", r)
+	}
+
+	now = now.Add(time.Minute)
+	_, ok = ss.Get("foo")
+	if ok {
+		t.Fatalf("expected value to be expired")
+	}
+	ss.Put("foo", "bar")
+	now = now.Add(time.Hour)
+	ss.Purge()
+	if len(ss.entries) != 0 {
+		t.Fatalf("expected all values purged, have %v", ss.entries)
+	}
+}
+
+func TestCacheShared(t *testing.T) {
+	ss := NewSharedStore()
+	ss.Declare("foo", time.Minute)
+	ca := NewCache()
+	if ca.PutShared("foo", "bar") {
+		t.Fatalf("expected process-wide store in use")
+	}
+	ca = ca.WithSharedStore(ss)
+	if !ca.PutShared("foo", "bar") {
+		t.Fatalf("expected value stored")
+	}
+	r, ok := NewCache().WithSharedStore(ss).GetShared("foo")
+	if !ok || r != "bar" {
+		t.Fatalf("expected 'bar', got '%s'", r)
+	}
+}
+
+func TestCacheSharedReference(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ss := NewSharedStore()
+	ss.now = func() time.Time {
+		return now
+	}
+	ss.Declare("foo", time.Minute)
+	ss.Put("foo", "barbarbar")
+	ca := NewCache().WithSharedStore(ss).WithCacheSize(16)
+	ca.Push()
+	err := ca.Add("foo", "barbarbar", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ca.Add("baz", "xyzzy", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca.Cache[1]["foo"] != "" {
+		t.Fatalf("expected shared value not to be copied to frame, got '%s'", ca.Cache[1]["foo"])
+	}
+	if ca.CacheUseSize != 14 {
+		t.Fatalf("expected use size 14, got %v", ca.CacheUseSize)
+	}
+	r, err := ca.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "barbarbar" {
+		t.Fatalf("expected 'barbarbar', got '%s'", r)
+	}
+	err = ca.Add("xyzzy", "plugh", 0)
+	if err == nil {
+		t.Fatalf("expected capacity error")
+	}
+
+	now = now.Add(time.Minute)
+	_, err = ca.Get("foo")
+	var serr *SharedExpiredError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected shared expired error, got %v", err)
+	}
+	ss.Put("foo", "bar")
+	err = ca.Update("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca.CacheUseSize != 8 {
+		t.Fatalf("expected use size 8, got %v", ca.CacheUseSize)
+	}
+	r, err = ca.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "bar" {
+		t.Fatalf("expected 'bar', got '%s'", r)
+	}
+
+	ca.Pop()
+	if ca.CacheUseSize != 0 {
+		t.Fatalf("expected use size 0, got %v", ca.CacheUseSize)
+	}
+	if len(ca.SharedSizes) != 0 {
+		t.Fatalf("expected shared sizes cleared, have %v", ca.SharedSizes)
+	}
+}
@@ -1,28 +1,45 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 
 	"git.defalsify.org/vise.git/asm"
 )
 
 func main() {
-	if (len(os.Args) < 2) {
+	var container bool
+	flag.BoolVar(&container, "container", false, "output bytecode in a container with versions, source hash and reference table")
+	flag.Parse()
+	if (flag.NArg() < 1) {
 		os.Exit(1)
 	}
-	fp := os.Args[1]
-	v, err := ioutil.ReadFile(fp)
+	fp := flag.Arg(0)
+	b := bytes.NewBuffer(nil)
+	_, err := asm.ParseFile(fp, b)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "read error: %v", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
-	n, err := asm.Parse(string(v), os.Stdout)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "parse error: %v", err)
-		os.Exit(1)
+	code := b.Bytes()
+	if container {
+		dir, name := filepath.Split(fp)
+		if dir == "" {
+			dir = "."
+		}
+		h, err := asm.SourceHash(os.DirFS(dir), name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		code, err = asm.Container(code, h, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
 	}
-	log.Printf("parsed total %v bytes", n)
+	os.Stdout.Write(code)
 }
@@ -1,27 +1,76 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"io/ioutil"
+	"strings"
 
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/state"
 	"git.defalsify.org/vise.git/vm"
 )
 
 func main() {
-	if (len(os.Args) < 2) {
+	var flagFile string
+	flag.StringVar(&flagFile, "flags", "", "assembly file with FLAG declarations to name flags by")
+	flag.Parse()
+	if (flag.NArg() < 1) {
 		os.Exit(1)
 	}
-	fp := os.Args[1]
+	if flagFile != "" {
+		f, err := os.Open(flagFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read error: %v", err)
+			os.Exit(1)
+		}
+		err = state.FlagDebugger.Load(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flag file error: %v", err)
+			os.Exit(1)
+		}
+	}
+	fp := flag.Arg(0)
 	v, err := ioutil.ReadFile(fp)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "read error: %v", err)
 		os.Exit(1)
 	}
-	r, err := vm.ToString(v)
+	h, code, err := bytecode.Decode(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error: %v", err)
+		os.Exit(1)
+	}
+	if h != nil {
+		fmt.Print(header(h))
+	}
+	r, err := vm.ToString(code)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "parse error: %v", err)
 		os.Exit(1)
 	}
 	fmt.Printf(r)
 }
+
+// container header as assembly comments.
+func header(h *bytecode.Header) string {
+	s := fmt.Sprintf("# vm version %d, assembler version %d\n# source %x\n", h.VmVersion, h.AsmVersion, h.SourceHash)
+	if h.Table == nil {
+		return s
+	}
+	var flags []string
+	for _, v := range h.Table.Flags {
+		f := fmt.Sprintf("%d", v)
+		name, ok := state.FlagDebugger.Name(v)
+		if ok {
+			f += "(" + name + ")"
+		}
+		flags = append(flags, f)
+	}
+	s += strings.TrimSpace("# symbols: " + strings.Join(h.Table.Symbols, " ")) + "\n"
+	s += strings.TrimSpace("# flags: " + strings.Join(flags, " ")) + "\n"
+	s += strings.TrimSpace("# selectors: " + strings.Join(h.Table.Selectors, " ")) + "\n"
+	return s
+}
@@ -4,9 +4,18 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"git.defalsify.org/vise.git/engine"
+	"git.defalsify.org/vise.git/state"
+)
+
+var (
+	tr *engine.Tracer
+	rr *engine.Recorder
+	traceFile string
+	recordFile string
 )
 
 func main() {
@@ -15,12 +24,25 @@ func main() {
 	var size uint
 	var sessionId string
 	var persist bool
+	var flagFile string
+	var cacheUsage bool
 	flag.StringVar(&dir, "d", ".", "resource dir to read from")
 	flag.UintVar(&size, "s", 0, "max size of output")
 	flag.StringVar(&root, "root", "root", "entry point symbol")
 	flag.StringVar(&sessionId, "session-id", "default", "session id")
 	flag.BoolVar(&persist, "persist", false, "use state persistence")
+	flag.StringVar(&traceFile, "trace", "", "write debug trace of session as JSON to file")
+	flag.StringVar(&recordFile, "record", "", "write session recording as JSON to file")
+	flag.StringVar(&flagFile, "flags", "", "assembly file with FLAG declarations to name flags by in debug trace")
+	flag.BoolVar(&cacheUsage, "cache-usage", false, "print cache usage to stderr after each execution")
 	flag.Parse()
+	if flagFile != "" {
+		err := loadFlags(flagFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flag file error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	fmt.Fprintf(os.Stderr, "starting session at symbol '%s' using resource dir: %s\n", root, dir)
 
 	ctx := context.Background()
@@ -29,23 +51,96 @@ func main() {
 		fmt.Fprintf(os.Stderr, "engine create error: %v", err)
 		os.Exit(1)
 	}
+	var enb *engine.Engine
+	switch e := en.(type) {
+	case *engine.Engine:
+		enb = e
+	case engine.PersistedEngine:
+		enb = e.Engine
+	}
+	if traceFile != "" {
+		tr = engine.NewTracer()
+		enb.WithTracer(tr)
+	}
+	if recordFile != "" {
+		rr = engine.NewRecorder()
+		enb.WithRecorder(rr)
+	}
+	if cacheUsage {
+		en = &usageEngine{
+			EngineIsh: en,
+			en: enb,
+		}
+	}
 	cont, err := en.Init(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "engine init exited with error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if !cont {
 		_, err = en.WriteResult(ctx, os.Stdout)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "dead init write error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		os.Stdout.Write([]byte{0x0a})
-		os.Exit(0)
+		exit(0)
 	}
 	err = engine.Loop(ctx, en, os.Stdin, os.Stdout)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "loop exited with error: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	exit(0)
+}
+
+// write debug trace and recording to file if active, then exit.
+func exit(code int) {
+	if tr != nil {
+		writeFile(traceFile, tr.WriteJSON)
+	}
+	if rr != nil {
+		writeFile(recordFile, rr.Recording().WriteJSON)
+	}
+	os.Exit(code)
+}
+
+func writeFile(fp string, fn func(w io.Writer) error) {
+	f, err := os.Create(fp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "file create error: %v\n", err)
+		return
+	}
+	defer f.Close()
+	err = fn(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "file write error: %v\n", err)
+	}
+}
+
+// engine printing the cache usage to stderr after each execution.
+type usageEngine struct {
+	engine.EngineIsh
+	en *engine.Engine
+}
+
+func(ue *usageEngine) Init(ctx context.Context) (bool, error) {
+	r, err := ue.EngineIsh.Init(ctx)
+	fmt.Fprint(os.Stderr, ue.en.CacheUsage())
+	return r, err
+}
+
+func(ue *usageEngine) Exec(ctx context.Context, input []byte) (bool, error) {
+	r, err := ue.EngineIsh.Exec(ctx, input)
+	fmt.Fprint(os.Stderr, ue.en.CacheUsage())
+	return r, err
+}
+
+func loadFlags(fp string) error {
+	f, err := os.Open(fp)
+	if err != nil {
+		return err
 	}
-}
\ No newline at end of file
+	defer f.Close()
+	return state.FlagDebugger.Load(f)
+}
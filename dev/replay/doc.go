@@ -0,0 +1,2 @@
+// Executable replay plays back a session recording against a resource directory, and reports the differences in rendered output.
+package main
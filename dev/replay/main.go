@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"git.defalsify.org/vise.git/engine"
+	"git.defalsify.org/vise.git/resource"
+)
+
+func main() {
+	var dir string
+	flag.StringVar(&dir, "d", ".", "resource dir to read from")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "usage: replay [-d <resource_dir>] <recording_file>\n")
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read error: %v\n", err)
+		os.Exit(1)
+	}
+	rec, err := engine.ReadRecording(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "recording parse error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	rs := resource.NewFsResource(dir)
+	r, err := engine.Replay(ctx, rec, rs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay error: %v\n", err)
+		os.Exit(1)
+	}
+	var fail int
+	for i, v := range r {
+		if v.Match() {
+			continue
+		}
+		fail += 1
+		fmt.Printf("step %d input '%s' differs:\n", i, v.Input)
+		if v.ExpectError != v.Error {
+			fmt.Printf("- error: %s\n+ error: %s\n", v.ExpectError, v.Error)
+		}
+		diff := v.Diff()
+		if diff != "" {
+			fmt.Println(diff)
+		}
+	}
+	if len(r) < len(rec.Steps) {
+		fmt.Printf("replay stopped after step %d of %d\n", len(r), len(rec.Steps))
+		fail += 1
+	}
+	if fail > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("all %d steps match\n", len(r))
+}
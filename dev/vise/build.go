@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.defalsify.org/vise.git/builder"
+	"git.defalsify.org/vise.git/lint"
+)
+
+func runBuild(args []string) int {
+	var dir string
+	var root string
+	var skip string
+	var force bool
+	var noLint bool
+	var container bool
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	fs.StringVar(&dir, "d", ".", "resource dir to build")
+	fs.StringVar(&root, "root", "root", "entry point symbol")
+	fs.StringVar(&skip, "skip", lint.CHECK_MISSING_FUNCTION, "comma separated list of lint checks to skip")
+	fs.BoolVar(&force, "f", false, "assemble all sources, also if unchanged")
+	fs.BoolVar(&noLint, "no-lint", false, "do not run the linter")
+	fs.BoolVar(&container, "container", false, "write bytecode in a container with versions, source hash and reference table")
+	fs.Parse(args)
+
+	cfg := builder.Config{
+		Dir: dir,
+		Root: root,
+		Force: force,
+		NoLint: noLint,
+		Container: container,
+	}
+	if skip != "" {
+		cfg.Skip = strings.Split(skip, ",")
+	}
+	ctx := context.Background()
+	r, err := builder.Build(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build error: %v\n", err)
+		return 1
+	}
+	fmt.Println(r)
+	if r.Failed() {
+		return 1
+	}
+	return 0
+}
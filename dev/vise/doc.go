@@ -0,0 +1,4 @@
+// Executable vise bundles development tools for vise applications under a single command.
+//
+// Run without arguments for a list of available subcommands.
+package main
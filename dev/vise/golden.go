@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"git.defalsify.org/vise.git/golden"
+	"git.defalsify.org/vise.git/resource"
+)
+
+func runGolden(args []string) int {
+	var dir string
+	var outDir string
+	var root string
+	var sizes string
+	var fixtureFile string
+	var flagCount uint
+	var update bool
+	fs := flag.NewFlagSet("golden", flag.ExitOnError)
+	fs.StringVar(&dir, "d", ".", "resource dir to read from")
+	fs.StringVar(&outDir, "o", "golden", "golden file dir")
+	fs.StringVar(&root, "root", "root", "entry point symbol")
+	fs.StringVar(&sizes, "s", "0", "comma separated list of output sizes to render")
+	fs.StringVar(&fixtureFile, "f", "", "JSON file with content for external symbols")
+	fs.UintVar(&flagCount, "flags", 0, "number of flags used in addition to the builtin flags")
+	fs.BoolVar(&update, "update", false, "write golden files instead of comparing")
+	fs.Parse(args)
+
+	cfg := golden.Config{
+		Root: root,
+		FlagCount: uint32(flagCount),
+	}
+	for _, v := range strings.Split(sizes, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid output size: %s\n", v)
+			return 1
+		}
+		cfg.Sizes = append(cfg.Sizes, uint32(n))
+	}
+	if fixtureFile != "" {
+		b, err := os.ReadFile(fixtureFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fixture read error: %v\n", err)
+			return 1
+		}
+		err = json.Unmarshal(b, &cfg.Fixtures)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fixture parse error: %v\n", err)
+			return 1
+		}
+	}
+
+	ctx := context.Background()
+	rs := resource.NewFsResource(dir)
+	snaps, err := golden.Snapshots(ctx, cfg, rs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "render error: %v\n", err)
+		return 1
+	}
+	if update {
+		err = golden.Write(outDir, snaps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "write error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("wrote %d golden files to %s\n", len(snaps), outDir)
+		return 0
+	}
+	r, err := golden.Compare(outDir, snaps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compare error: %v\n", err)
+		return 1
+	}
+	for _, v := range r {
+		fmt.Println(v)
+	}
+	if len(r) > 0 {
+		return 1
+	}
+	fmt.Printf("all %d snapshots match\n", len(snaps))
+	return 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"git.defalsify.org/vise.git/graph"
+	"git.defalsify.org/vise.git/resource"
+)
+
+func runGraph(args []string) int {
+	var dir string
+	var root string
+	var format string
+	var src bool
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.StringVar(&dir, "d", ".", "resource dir to read from")
+	fs.StringVar(&root, "root", "root", "entry point symbol")
+	fs.StringVar(&format, "format", "dot", "output format (dot or mermaid)")
+	fs.BoolVar(&src, "src", false, "read assembly sources (.vis) instead of bytecode (.bin)")
+	fs.Parse(args)
+
+	var g *graph.Graph
+	var err error
+	if src {
+		g, err = graph.FromSource(dir, root)
+	} else {
+		g, err = graph.FromResource(resource.NewFsResource(dir), root)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graph error: %v\n", err)
+		return 1
+	}
+	switch format {
+	case "dot":
+		err = g.WriteDot(os.Stdout)
+	case "mermaid":
+		err = g.WriteMermaid(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format: %s\n", format)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write error: %v\n", err)
+		return 1
+	}
+	for _, v := range g.Missing {
+		fmt.Fprintf(os.Stderr, "warning: missing node: %s\n", v)
+	}
+	return 0
+}
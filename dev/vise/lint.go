@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.defalsify.org/vise.git/lint"
+	"git.defalsify.org/vise.git/resource"
+)
+
+func runLint(args []string) int {
+	var dir string
+	var srcDir string
+	var root string
+	var skip string
+	var flagCount uint
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.StringVar(&dir, "d", ".", "resource dir to read from")
+	fs.StringVar(&srcDir, "src", "", "assembly source dir, for line numbers (default is resource dir)")
+	fs.StringVar(&root, "root", "root", "entry point symbol")
+	fs.StringVar(&skip, "skip", "", "comma separated list of checks to skip")
+	fs.UintVar(&flagCount, "flags", 0, "number of flags used in addition to the builtin flags")
+	fs.Parse(args)
+
+	if srcDir == "" {
+		srcDir = dir
+	}
+	cfg := lint.Config{
+		Root: root,
+		SourceDir: srcDir,
+		FlagCount: uint32(flagCount),
+	}
+	if skip != "" {
+		cfg.Skip = strings.Split(skip, ",")
+	}
+	ctx := context.Background()
+	rs := resource.NewFsResource(dir)
+	r, err := lint.Lint(ctx, cfg, rs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint error: %v\n", err)
+		return 1
+	}
+	for _, v := range r {
+		fmt.Println(v)
+	}
+	if len(r) > 0 {
+		return 1
+	}
+	return 0
+}
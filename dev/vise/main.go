@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// subcommand executes with the command line arguments following the subcommand name, and returns the exit code.
+type subcommand struct {
+	run func(args []string) int
+	help string
+}
+
+var cmds = map[string]subcommand{
+	"build": {runBuild, "assemble changed sources, validate templates and lint a resource directory"},
+	"golden": {runGolden, "render snapshots of all nodes and compare them against golden files"},
+	"graph": {runGraph, "export the navigation graph as Graphviz DOT or Mermaid"},
+	"lint": {runLint, "check a resource directory for common mistakes"},
+	"pack": {runPack, "pack a resource directory into a single bundle file"},
+	"test": {runTest, "run scenario test files against a resource directory"},
+}
+
+func usage() {
+	var names []string
+	for k := range cmds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "usage: vise <command> [arguments]\n\ncommands:\n")
+	for _, v := range names {
+		fmt.Fprintf(os.Stderr, "\t%-8s%s\n", v, cmds[v].help)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+	cmd, ok := cmds[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+		usage()
+		os.Exit(1)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.defalsify.org/vise.git/resource"
+)
+
+func runPack(args []string) int {
+	var dir string
+	var out string
+	var exclude string
+	fs := flag.NewFlagSet("pack", flag.ExitOnError)
+	fs.StringVar(&dir, "d", ".", "resource dir to pack")
+	fs.StringVar(&out, "o", "bundle.zip", "bundle file to write")
+	fs.StringVar(&exclude, "x", "", "comma separated list of file name patterns to exclude")
+	fs.Parse(args)
+
+	var x []string
+	if exclude != "" {
+		x = strings.Split(exclude, ",")
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bundle create error: %v\n", err)
+		return 1
+	}
+	mf, err := resource.WriteBundle(f, dir, x)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bundle write error: %v\n", err)
+		os.Remove(out)
+		return 1
+	}
+	var size int64
+	for _, v := range mf.Files {
+		size += v.Size
+	}
+	fmt.Printf("packed %d files (%d bytes) from %s to %s\n", len(mf.Files), size, dir, out)
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"git.defalsify.org/vise.git/scenario"
+)
+
+func runTest(args []string) int {
+	var dir string
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	fs.StringVar(&dir, "d", ".", "resource dir to read from")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "usage: vise test [-d <resource_dir>] <scenario_file> ...\n")
+		return 1
+	}
+
+	ctx := context.Background()
+	var fail int
+	for _, fp := range fs.Args() {
+		sc, err := scenario.ReadFile(fp)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scenario parse error: %v\n", err)
+			fail += 1
+			continue
+		}
+		rp, err := scenario.RunDir(ctx, sc, dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scenario run error: %v\n", err)
+			fail += 1
+			continue
+		}
+		if rp.Failed() != nil {
+			fail += 1
+		}
+		fmt.Println(rp)
+	}
+	if fail > 0 {
+		return 1
+	}
+	return 0
+}
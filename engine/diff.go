@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"strings"
+)
+
+// Diff returns a line by line comparison of two rendered outputs.
+//
+// Lines only in expect are prefixed with "- ", lines only in got with "+ ", and common lines with "  ".
+//
+// An empty string is returned if the outputs are identical.
+func Diff(expect string, got string) string {
+	if expect == got {
+		return ""
+	}
+	a := strings.Split(expect, "\n")
+	b := strings.Split(got, "\n")
+
+	// longest common subsequence lengths of all suffixes.
+	lcs := make([][]int, len(a) + 1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b) + 1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var r []string
+	var i int
+	var j int
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			r = append(r, "  " + a[i])
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			r = append(r, "- " + a[i])
+			i++
+		} else {
+			r = append(r, "+ " + b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		r = append(r, "- " + a[i])
+	}
+	for ; j < len(b); j++ {
+		r = append(r, "+ " + b[j])
+	}
+	return strings.Join(r, "\n")
+}
@@ -39,10 +39,19 @@ type Engine struct {
 	root string
 	session string
 	initd bool
+	cfg Config
+	tracer *Tracer
+	recorder *Recorder
 }
 
 // NewEngine creates a new Engine
+//
+// If the resource implements resource.Snapshotter, the engine uses the snapshot of the resource at the time it is created.
 func NewEngine(ctx context.Context, cfg Config, st *state.State, rs resource.Resource, ca cache.Memory) Engine {
+	sr, ok := rs.(resource.Snapshotter)
+	if ok {
+		rs = sr.Snapshot()
+	}
 	var szr *render.Sizer
 	if cfg.OutputSize > 0 {
 		szr = render.NewSizer(cfg.OutputSize)
@@ -55,6 +64,7 @@ func NewEngine(ctx context.Context, cfg Config, st *state.State, rs resource.Res
 		vm: vm.NewVm(st, rs, ca, szr),
 	}
 	engine.root = cfg.Root	
+	engine.cfg = cfg
 	engine.session = cfg.SessionId
 
 	var err error
@@ -74,6 +84,41 @@ func NewEngine(ctx context.Context, cfg Config, st *state.State, rs resource.Res
 	return engine
 }
 
+// WithTracer activates recording of the debug state of every execution step to the given Tracer.
+func(en *Engine) WithTracer(tr *Tracer) *Engine {
+	tr.Session = en.session
+	en.tracer = tr
+	en.vm = en.vm.WithInstructionFunc(tr.instruction)
+	return en
+}
+
+// WithRecorder activates recording of client inputs and external symbol results to the given Recorder.
+//
+// The recording can be played back with Replay.
+func(en *Engine) WithRecorder(rr *Recorder) *Engine {
+	cfg := en.cfg
+	cfg.FlagCount = en.st.BitSize - 8
+	im, ok := en.ca.(cache.InspectableMemory)
+	if ok {
+		cfg.CacheSize = im.Inspect().Limit
+	}
+	rr.rec.Config = cfg
+	en.recorder = rr
+	en.vm = en.vm.WithResultFunc(rr.result)
+	return en
+}
+
+// CacheUsage returns the symbols currently loaded in the cache, and their size usage.
+//
+// The usage is empty if the cache does not support inspection.
+func(en *Engine) CacheUsage() cache.Usage {
+	im, ok := en.ca.(cache.InspectableMemory)
+	if !ok {
+		return cache.Usage{}
+	}
+	return im.Inspect()
+}
+
 // Finish implements EngineIsh interface
 func(en *Engine) Finish() error {
 	Logg.Tracef("that's a wrap", "engine", en)
@@ -96,6 +141,13 @@ func(en *Engine) restore() {
 //
 // It loads and executes code for the start node.
 func(en *Engine) Init(ctx context.Context) (bool, error) {
+	return en.traced(nil, true, func() (bool, error) {
+		return en.init(ctx)
+	})
+}
+
+// backend for Init
+func(en *Engine) init(ctx context.Context) (bool, error) {
 	en.restore()
 	if en.initd {
 		Logg.DebugCtxf(ctx, "already initialized")
@@ -138,6 +190,13 @@ func(en *Engine) Init(ctx context.Context) (bool, error) {
 // - no current bytecode is available
 // - input processing against bytcode failed
 func (en *Engine) Exec(ctx context.Context, input []byte) (bool, error) {
+	return en.traced(input, false, func() (bool, error) {
+		return en.execInput(ctx, input)
+	})
+}
+
+// backend for Exec, before the input validity check
+func (en *Engine) execInput(ctx context.Context, input []byte) (bool, error) {
 	var err error
 	if en.st.Language != nil {
 		ctx = context.WithValue(ctx, "Language", *en.st.Language)
@@ -210,9 +269,35 @@ func(en *Engine) WriteResult(ctx context.Context, w io.Writer) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	if en.tracer != nil {
+		en.tracer.output(r)
+	}
+	if en.recorder != nil {
+		en.recorder.output(r)
+	}
 	return io.WriteString(w, r)
 }
 
+// execute fn as a single step in the tracer and recorder, if active.
+func(en *Engine) traced(input []byte, init bool, fn func() (bool, error)) (bool, error) {
+	var tracing bool
+	var recording bool
+	if en.tracer != nil {
+		tracing = en.tracer.begin(input, en.st)
+	}
+	if en.recorder != nil {
+		recording = en.recorder.begin(input, init)
+	}
+	cont, err := fn()
+	if tracing {
+		en.tracer.end(en.st, en.ca, err)
+	}
+	if recording {
+		en.recorder.end(err)
+	}
+	return cont, err
+}
+
 // start execution over at top node while keeping current state of client error flags.
 func(en *Engine) reset(ctx context.Context) (bool, error) {
 	var err error
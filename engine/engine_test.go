@@ -350,3 +350,65 @@ func TestConfigLanguageRender(t *testing.T) {
 		t.Fatalf("expected:\n\t%s\ngot:\n\t%s", expect, r)
 	}
 }
+
+func TestEngineSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	code := vm.NewLine(nil, vm.HALT, nil, nil, nil)
+	err := ioutil.WriteFile(path.Join(dir, "root.bin"), code, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "root"), []byte("hello"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := resource.NewReloadResource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		Root: "root",
+	}
+	st := state.NewState(0)
+	en := NewEngine(ctx, cfg, &st, rs, cache.NewCache())
+	_, err = en.Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path.Join(dir, "root"), []byte("hello again"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := rs.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("expected reload")
+	}
+	w := bytes.NewBuffer(nil)
+	_, err = en.WriteResult(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "hello" {
+		t.Fatalf("expected output from snapshot at engine creation, got '%s'", w)
+	}
+
+	st = state.NewState(0)
+	en = NewEngine(ctx, cfg, &st, rs, cache.NewCache())
+	_, err = en.Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = bytes.NewBuffer(nil)
+	_, err = en.WriteResult(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "hello again" {
+		t.Fatalf("expected output from new snapshot, got '%s'", w)
+	}
+}
@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("engine")
 )
+
+func init() {
+	logging.Register("engine", &Logg)
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"git.defalsify.org/vise.git/resource"
+)
+
+// RecordResult holds the result of a single external symbol resolution in a recorded session.
+type RecordResult struct {
+	Symbol string `json:"symbol"`
+	Content string `json:"content"`
+	Status int `json:"status,omitempty"`
+	FlagSet []uint32 `json:"flag_set,omitempty"`
+	FlagReset []uint32 `json:"flag_reset,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// RecordStep holds the client input, external symbol results and rendered output of a single execution in a recorded session.
+type RecordStep struct {
+	Init bool `json:"init,omitempty"` // Step was executed with Engine.Init
+	Input string `json:"input"`
+	Results []RecordResult `json:"results"`
+	Output string `json:"output"`
+	Error string `json:"error,omitempty"`
+}
+
+// Recording is a complete recorded session, which can be played back with Replay.
+type Recording struct {
+	Config Config `json:"config"`
+	Steps []RecordStep `json:"steps"`
+}
+
+// WriteJSON writes the recording as JSON to the given writer.
+func(rec *Recording) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(rec)
+}
+
+// ReadRecording reads a JSON recording written by Recording.WriteJSON.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	rec := &Recording{}
+	err := json.NewDecoder(r).Decode(rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Recorder records the client inputs, external symbol results and rendered outputs of an engine session.
+//
+// A Recorder is activated with Engine.WithRecorder.
+type Recorder struct {
+	rec Recording
+	current *RecordStep
+}
+
+// NewRecorder creates a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Recording returns the session recorded so far.
+func(rr *Recorder) Recording() *Recording {
+	return &rr.rec
+}
+
+// start recording a new step.
+//
+// returns false if a step is already being recorded.
+func(rr *Recorder) begin(input []byte, init bool) bool {
+	if rr.current != nil {
+		return false
+	}
+	rr.current = &RecordStep{
+		Init: init,
+		Input: string(input),
+		Results: []RecordResult{},
+	}
+	return true
+}
+
+// finish recording the current step.
+func(rr *Recorder) end(err error) {
+	if rr.current == nil {
+		return
+	}
+	if err != nil {
+		rr.current.Error = err.Error()
+	}
+	rr.rec.Steps = append(rr.rec.Steps, *rr.current)
+	rr.current = nil
+}
+
+// record an external symbol result in the current step.
+func(rr *Recorder) result(ctx context.Context, sym string, r resource.Result, err error) {
+	if rr.current == nil {
+		return
+	}
+	rs := RecordResult{
+		Symbol: sym,
+		Content: r.Content,
+		Status: r.Status,
+		FlagSet: r.FlagSet,
+		FlagReset: r.FlagReset,
+	}
+	if err != nil {
+		rs.Error = err.Error()
+	}
+	rr.current.Results = append(rr.current.Results, rs)
+}
+
+// record the rendered output of the last step.
+func(rr *Recorder) output(s string) {
+	l := len(rr.rec.Steps)
+	if l == 0 {
+		return
+	}
+	rr.rec.Steps[l-1].Output += s
+}
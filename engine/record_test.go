@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/cache"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/state"
+)
+
+type templateOverrideResource struct {
+	resource.Resource
+	sym string
+	tpl string
+}
+
+func(rs templateOverrideResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	if sym == rs.sym {
+		return rs.tpl, nil
+	}
+	return rs.Resource.GetTemplate(ctx, sym)
+}
+
+func recordTestSession(t *testing.T) *Recording {
+	ctx := context.TODO()
+	st := state.NewState(17)
+	rs := NewFsWrapper(dataDir, &st)
+	ca := cache.NewCache().WithCacheSize(1024)
+	cfg := Config{
+		Root: "root",
+		SessionId: "xyzzy",
+	}
+	en := NewEngine(ctx, cfg, &st, &rs, ca)
+	rr := NewRecorder()
+	en.WithRecorder(rr)
+	_, err := en.Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bytes.NewBuffer(nil)
+	_, err = en.WriteResult(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = en.Exec(ctx, []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = en.WriteResult(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr.Recording()
+}
+
+func TestRecord(t *testing.T) {
+	generateTestData(t)
+	rec := recordTestSession(t)
+	if rec.Config.FlagCount != 17 {
+		t.Fatalf("expected flag count 17, got %v", rec.Config.FlagCount)
+	}
+	if rec.Config.CacheSize != 1024 {
+		t.Fatalf("expected cache size 1024, got %v", rec.Config.CacheSize)
+	}
+	if len(rec.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(rec.Steps))
+	}
+	if !rec.Steps[0].Init {
+		t.Fatalf("expected first step to be init")
+	}
+	step := rec.Steps[1]
+	if step.Input != "1" {
+		t.Fatalf("expected input '1', got '%s'", step.Input)
+	}
+	if len(step.Results) != 1 {
+		t.Fatalf("expected 1 result, got %v", step.Results)
+	}
+	if step.Results[0].Symbol != "inky" || step.Results[0].Content != "one" {
+		t.Fatalf("expected result 'one' for 'inky', got %v", step.Results[0])
+	}
+	if !strings.HasPrefix(step.Output, "this is in foo") {
+		t.Fatalf("expected foo output, got '%s'", step.Output)
+	}
+
+	w := bytes.NewBuffer(nil)
+	err := rec.WriteJSON(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recNew, err := ReadRecording(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recNew.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(recNew.Steps))
+	}
+	if recNew.Steps[1].Output != step.Output {
+		t.Fatalf("expected output '%s', got '%s'", step.Output, recNew.Steps[1].Output)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	generateTestData(t)
+	ctx := context.TODO()
+	rec := recordTestSession(t)
+
+	// no entry functions are registered, results must come from the recording.
+	mem := resource.NewMemResource()
+	rs := resource.NewFsResource(dataDir)
+	mem.WithCodeGetter(rs.GetCode)
+	mem.WithTemplateGetter(rs.GetTemplate)
+	mem.WithMenuGetter(rs.GetMenu)
+
+	r, err := Replay(ctx, rec, &mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(r))
+	}
+	for i, v := range r {
+		if !v.Match() {
+			t.Fatalf("step %d mismatch:\n%s\nerror: %s", i, v.Diff(), v.Error)
+		}
+	}
+
+	rso := templateOverrideResource{
+		Resource: &mem,
+		sym: "foo",
+		tpl: "this is in foo\nit has changed",
+	}
+	r, err = Replay(ctx, rec, rso)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r[0].Match() {
+		t.Fatalf("expected first step to match")
+	}
+	if r[1].Match() {
+		t.Fatalf("expected second step mismatch")
+	}
+	diff := r[1].Diff()
+	if !strings.Contains(diff, "- it has more lines\n+ it has changed") {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	r := Diff("foo\nbar\nbaz", "foo\nbar\nbaz")
+	if r != "" {
+		t.Fatalf("expected empty diff, got '%s'", r)
+	}
+	r = Diff("foo\nbar\nbaz", "foo\nxyzzy\nbaz\nquux")
+	expect := "  foo\n- bar\n+ xyzzy\n  baz\n+ quux"
+	if r != expect {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expect, r)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"git.defalsify.org/vise.git/cache"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/state"
+)
+
+// ReplayStep holds the recorded and the replayed outcome of a single step of a recorded session.
+type ReplayStep struct {
+	Input string
+	Expect string // Output in the recording.
+	Output string // Output from the replay.
+	ExpectError string // Error in the recording.
+	Error string // Error from the replay.
+}
+
+// Diff returns a line by line comparison of the recorded and the replayed output.
+//
+// An empty string is returned if the outputs are identical.
+func(rs ReplayStep) Diff() string {
+	return Diff(rs.Expect, rs.Output)
+}
+
+// Match returns true if the replay reproduced the recorded output and error.
+func(rs ReplayStep) Match() bool {
+	return rs.Expect == rs.Output && rs.ExpectError == rs.Error
+}
+
+// resource that plays back recorded external symbol results.
+type replayResource struct {
+	resource.Resource
+	results map[string][]RecordResult
+}
+
+// set the recorded results for the step about to be executed.
+func(rr *replayResource) load(step RecordStep) {
+	rr.results = make(map[string][]RecordResult)
+	for _, v := range step.Results {
+		rr.results[v.Symbol] = append(rr.results[v.Symbol], v)
+	}
+}
+
+// FuncFor implements resource.Resource.
+//
+// Fails if no more results have been recorded for the symbol in the current step.
+func(rr *replayResource) FuncFor(sym string) (resource.EntryFunc, error) {
+	if len(rr.results[sym]) == 0 {
+		return nil, fmt.Errorf("no recorded result for sym: %s", sym)
+	}
+	return rr.get, nil
+}
+
+// play back the next recorded result for the symbol.
+func(rr *replayResource) get(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	q := rr.results[sym]
+	if len(q) == 0 {
+		return resource.Result{}, fmt.Errorf("no recorded result for sym: %s", sym)
+	}
+	v := q[0]
+	rr.results[sym] = q[1:]
+	r := resource.Result{
+		Content: v.Content,
+		Status: v.Status,
+		FlagSet: v.FlagSet,
+		FlagReset: v.FlagReset,
+	}
+	if v.Error != "" {
+		return r, errors.New(v.Error)
+	}
+	return r, nil
+}
+
+// Replay plays back a recorded session.
+//
+// Bytecode, templates and menus are retrieved from the given resource, whereas the results of all external symbols are played back from the recording. This makes it possible to verify the rendered outputs of a session after a change to the bytecode or the templates.
+//
+// Playback stops at the first step that returns an error.
+func Replay(ctx context.Context, rec *Recording, rs resource.Resource) ([]ReplayStep, error) {
+	var r []ReplayStep
+	rsr := &replayResource{
+		Resource: rs,
+	}
+	st := state.NewState(rec.Config.FlagCount)
+	ca := cache.NewCache().WithSharedStore(cache.NewSharedStore()).WithCacheSize(rec.Config.CacheSize)
+	en := NewEngine(ctx, rec.Config, &st, rsr, ca)
+	for _, v := range rec.Steps {
+		var err error
+		rsr.load(v)
+		step := ReplayStep{
+			Input: v.Input,
+			Expect: v.Output,
+			ExpectError: v.Error,
+		}
+		if v.Init {
+			_, err = en.Init(ctx)
+		} else {
+			_, err = en.Exec(ctx, []byte(v.Input))
+		}
+		if err != nil {
+			step.Error = err.Error()
+			r = append(r, step)
+			break
+		}
+		w := bytes.NewBuffer(nil)
+		_, err = en.WriteResult(ctx, w)
+		if err != nil {
+			return r, err
+		}
+		step.Output = w.String()
+		r = append(r, step)
+	}
+	return r, nil
+}
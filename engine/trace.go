@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"git.defalsify.org/vise.git/cache"
+	"git.defalsify.org/vise.git/state"
+)
+
+// TraceStep holds the debug state recorded for a single engine execution.
+type TraceStep struct {
+	Input string `json:"input"` // Client input for the execution.
+	Instructions []string `json:"instructions"` // All instructions executed, as assembly code.
+	FlagsBefore string `json:"flags_before"` // Flags set before execution.
+	FlagsAfter string `json:"flags_after"` // Flags set after execution.
+	ExecPath []string `json:"exec_path"` // Node stack after execution.
+	Cache []map[string]string `json:"cache,omitempty"` // Cache frame contents after execution.
+	CacheUsage *cache.Usage `json:"cache_usage,omitempty"` // Cache size usage after execution.
+	Output string `json:"output"` // Rendered output after execution.
+	Error string `json:"error,omitempty"` // Error returned by the execution, if any.
+}
+
+// Tracer records the debug state of every execution step of an engine.
+//
+// A Tracer is activated with Engine.WithTracer.
+type Tracer struct {
+	Session string `json:"session"`
+	Steps []TraceStep `json:"steps"`
+	current *TraceStep
+}
+
+// NewTracer creates a new Tracer.
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+// WriteJSON writes the recorded trace as JSON to the given writer.
+func(tr *Tracer) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(tr)
+}
+
+// start recording a new step.
+//
+// returns false if a step is already being recorded.
+func(tr *Tracer) begin(input []byte, st *state.State) bool {
+	if tr.current != nil {
+		return false
+	}
+	tr.current = &TraceStep{
+		Input: string(input),
+		Instructions: []string{},
+		FlagsBefore: flagsString(st),
+	}
+	return true
+}
+
+// finish recording the current step.
+func(tr *Tracer) end(st *state.State, ca cache.Memory, err error) {
+	step := tr.current
+	tr.current = nil
+	if step == nil {
+		return
+	}
+	step.FlagsAfter = flagsString(st)
+	step.ExecPath = append([]string{}, st.ExecPath...)
+	im, ok := ca.(cache.InspectableMemory)
+	if ok {
+		u := im.Inspect()
+		step.Cache = cacheFrames(ca, u)
+		step.CacheUsage = &u
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	tr.Steps = append(tr.Steps, *step)
+}
+
+// record an instruction executed during the current step.
+func(tr *Tracer) instruction(ctx context.Context, instruction string) {
+	if tr.current == nil {
+		return
+	}
+	tr.current.Instructions = append(tr.current.Instructions, instruction)
+}
+
+// record the rendered output of the last step.
+func(tr *Tracer) output(s string) {
+	l := len(tr.Steps)
+	if l == 0 {
+		return
+	}
+	tr.Steps[l-1].Output += s
+}
+
+// human readable representation of the flags set in the state.
+func flagsString(st *state.State) string {
+	return state.FlagDebugger.AsString(st.Flags, st.BitSize - 8)
+}
+
+// copy of the cache frame contents.
+func cacheFrames(ca cache.Memory, u cache.Usage) []map[string]string {
+	var r []map[string]string
+	for _, fr := range u.Frames {
+		m := make(map[string]string)
+		for _, e := range fr.Entries {
+			v, err := ca.Get(e.Key)
+			if err != nil {
+				continue
+			}
+			m[e.Key] = v
+		}
+		r = append(r, m)
+	}
+	return r
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/cache"
+	"git.defalsify.org/vise.git/state"
+)
+
+func TestTracer(t *testing.T) {
+	generateTestData(t)
+	ctx := context.TODO()
+	st := state.NewState(17)
+	rs := NewFsWrapper(dataDir, &st)
+	ca := cache.NewCache().WithCacheSize(1024)
+	cfg := Config{
+		Root: "root",
+		SessionId: "xyzzy",
+	}
+	en := NewEngine(ctx, cfg, &st, &rs, ca)
+	tr := NewTracer()
+	en.WithTracer(tr)
+	_, err := en.Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bytes.NewBuffer(nil)
+	_, err = en.WriteResult(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = en.Exec(ctx, []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = bytes.NewBuffer(nil)
+	_, err = en.WriteResult(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tr.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(tr.Steps))
+	}
+	step := tr.Steps[0]
+	if step.Instructions[0] != "MOVE root" {
+		t.Fatalf("expected first instruction 'MOVE root', got '%s'", step.Instructions[0])
+	}
+	if !strings.HasPrefix(step.Output, "hello world") {
+		t.Fatalf("expected init output, got '%s'", step.Output)
+	}
+
+	step = tr.Steps[1]
+	if step.Input != "1" {
+		t.Fatalf("expected input '1', got '%s'", step.Input)
+	}
+	if strings.Join(step.ExecPath, "/") != "root/foo" {
+		t.Fatalf("expected exec path 'root/foo', got %v", step.ExecPath)
+	}
+	if !strings.Contains(step.FlagsAfter, "INTERNAL_WAIT") {
+		t.Fatalf("expected wait flag after execution, got '%s'", step.FlagsAfter)
+	}
+	if step.Output != w.String() {
+		t.Fatalf("expected output '%s', got '%s'", w.String(), step.Output)
+	}
+	l := len(step.Cache)
+	if l == 0 || step.Cache[l-1]["inky"] != "one" {
+		t.Fatalf("expected inky loaded in top frame, got %v", step.Cache)
+	}
+	u := step.CacheUsage
+	if u == nil || len(u.Frames) != l {
+		t.Fatalf("expected cache usage for %d frames, got %v", l, u)
+	}
+	e := u.Frames[l-1].Entries
+	if len(e) != 1 || e[0].Key != "inky" || e[0].Size != 3 || e[0].Limit != 20 {
+		t.Fatalf("unexpected cache usage in top frame: %v", e)
+	}
+	var haveLoad bool
+	for _, v := range step.Instructions {
+		if v == "LOAD inky 20" {
+			haveLoad = true
+		}
+	}
+	if !haveLoad {
+		t.Fatalf("expected LOAD instruction in trace, got %v", step.Instructions)
+	}
+
+	w = bytes.NewBuffer(nil)
+	err = tr.WriteJSON(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trNew := NewTracer()
+	err = json.Unmarshal(w.Bytes(), trNew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trNew.Session != "xyzzy" {
+		t.Fatalf("expected session 'xyzzy', got '%s'", trNew.Session)
+	}
+	if len(trNew.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(trNew.Steps))
+	}
+}
+
+// memory without inspection support.
+type plainMemory struct {
+	cache.Memory
+}
+
+func TestTracerPlainMemory(t *testing.T) {
+	generateTestData(t)
+	ctx := context.TODO()
+	st := state.NewState(17)
+	rs := NewFsWrapper(dataDir, &st)
+	ca := plainMemory{
+		Memory: cache.NewCache().WithCacheSize(1024),
+	}
+	cfg := Config{
+		Root: "root",
+	}
+	en := NewEngine(ctx, cfg, &st, &rs, ca)
+	tr := NewTracer()
+	en.WithTracer(tr)
+	en.WithRecorder(NewRecorder())
+	_, err := en.Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Steps) != 1 {
+		t.Fatalf("expected 1 step, got %d", len(tr.Steps))
+	}
+	if tr.Steps[0].CacheUsage != nil || len(tr.Steps[0].Cache) != 0 {
+		t.Fatalf("expected no cache in trace, got %v", tr.Steps[0])
+	}
+	u := en.CacheUsage()
+	if len(u.Frames) != 0 {
+		t.Fatalf("expected empty cache usage, got %v", u)
+	}
+}
@@ -18,11 +18,16 @@ import (
 	testdataloader "github.com/peteole/testdata-loader"
 )
 
+// flags.vis is generated from the registry with the -write-flags option.
+var (
+	flags                   = state.NewFlagRegistry()
+	USERFLAG_HASACCEPTED    = flags.MustAdd("has_accepted")
+	USERFLAG_HASSESSION     = flags.MustAdd("has_session")
+	USERFLAG_HASACCOUNT     = flags.MustAdd("has_account")
+	USERFLAG_ACCOUNTSUCCESS = flags.MustAdd("account_success")
+)
+
 const (
-	USERFLAG_HASACCEPTED    = state.FLAG_USERSTART
-	USERFLAG_HASSESSION     = state.FLAG_USERSTART
-	USERFLAG_HASACCOUNT     = state.FLAG_USERSTART
-	USERFLAG_ACCOUNTSUCCESS = state.FLAG_USERSTART
 	createAccountURL        = "https://custodial.sarafu.africa/api/account/create"
 	trackStatusURL          = "https://custodial.sarafu.africa/api/track/"
 )
@@ -350,13 +355,28 @@ func main() {
 	var root string
 	var size uint
 	var sessionId string
+	var writeFlags bool
 	flag.UintVar(&size, "s", 0, "max size of output")
 	flag.StringVar(&root, "root", "root", "entry point symbol")
 	flag.StringVar(&sessionId, "session-id", "default", "session id")
+	flag.BoolVar(&writeFlags, "write-flags", false, "write flag declarations for the assembler to stdout and exit")
 	flag.Parse()
+	if writeFlags {
+		_, err := flags.WriteTo(os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "write flags fail: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	fmt.Fprintf(os.Stderr, "starting session at symbol '%s' using resource dir: %s\n", root, dir)
 
-	st := state.NewState(1)
+	err := flags.Debug()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flag debug fail: %v\n", err)
+		os.Exit(1)
+	}
+	st := state.NewState(flags.Count())
 	rsf := resource.NewFsResource(scriptDir)
 	rs := accountResource{rsf, &st}
 	rs.AddLocalFunc("check_session", rs.check_session)
@@ -374,7 +394,6 @@ func main() {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, "SessionId", sessionId)
 	en := engine.NewEngine(ctx, cfg, &st, rs, ca)
-	var err error
 	_, err = en.Init(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "engine init fail: %v\n", err)
@@ -1,6 +1,6 @@
 module git.defalsify.org/vise.git
 
-go 1.20
+go 1.21
 
 require (
 	github.com/alecthomas/participle/v2 v2.0.0
@@ -0,0 +1,2 @@
+// Package golden renders snapshots of every node reachable in a vise application, and compares them against stored golden files.
+package golden
@@ -0,0 +1,108 @@
+package golden
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"git.defalsify.org/vise.git/engine"
+)
+
+// Mismatch describes a difference between a rendered snapshot and its golden file.
+type Mismatch struct {
+	Name string // Golden file name.
+	Diff string // Line diff between golden file and rendered snapshot. Empty if the golden file is missing or stale.
+	Missing bool // No golden file exists for the snapshot.
+	Stale bool // Golden file exists, but no snapshot was rendered for it.
+}
+
+// String implements the String interface.
+func(m Mismatch) String() string {
+	if m.Missing {
+		return fmt.Sprintf("%s: missing golden file", m.Name)
+	}
+	if m.Stale {
+		return fmt.Sprintf("%s: no longer rendered", m.Name)
+	}
+	return fmt.Sprintf("%s differs:\n%s", m.Name, m.Diff)
+}
+
+// Write writes the snapshots as golden files to the given directory, replacing existing golden files.
+//
+// Golden files for which no snapshot exists are removed.
+func Write(dir string, snaps []Snapshot) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, v := range snaps {
+		fp := path.Join(dir, v.Name())
+		err = os.WriteFile(fp, []byte(v.String()), 0644)
+		if err != nil {
+			return err
+		}
+		names[v.Name()] = true
+	}
+	stale, err := staleFiles(dir, names)
+	if err != nil {
+		return err
+	}
+	for _, v := range stale {
+		err = os.Remove(path.Join(dir, v))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compare compares the snapshots against the golden files in the given directory.
+//
+// An empty result means all snapshots match.
+func Compare(dir string, snaps []Snapshot) ([]Mismatch, error) {
+	var r []Mismatch
+	names := make(map[string]bool)
+	for _, v := range snaps {
+		names[v.Name()] = true
+		fp := path.Join(dir, v.Name())
+		b, err := os.ReadFile(fp)
+		if os.IsNotExist(err) {
+			r = append(r, Mismatch{Name: v.Name(), Missing: true})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		diff := engine.Diff(string(b), v.String())
+		if diff != "" {
+			r = append(r, Mismatch{Name: v.Name(), Diff: diff})
+		}
+	}
+	stale, err := staleFiles(dir, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range stale {
+		r = append(r, Mismatch{Name: v, Stale: true})
+	}
+	return r, nil
+}
+
+// golden files in the directory that are not in the given set of names.
+func staleFiles(dir string, names map[string]bool) ([]string, error) {
+	var r []string
+	fps, err := filepath.Glob(path.Join(dir, "*.golden"))
+	if err != nil {
+		return nil, err
+	}
+	for _, fp := range fps {
+		v := filepath.Base(fp)
+		if !names[v] {
+			r = append(r, v)
+		}
+	}
+	sort.Strings(r)
+	return r, nil
+}
@@ -0,0 +1,240 @@
+package golden
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"git.defalsify.org/vise.git/cache"
+	"git.defalsify.org/vise.git/graph"
+	"git.defalsify.org/vise.git/render"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/state"
+	"git.defalsify.org/vise.git/vm"
+)
+
+// Config defines which nodes and output sizes to render snapshots for.
+type Config struct {
+	Root string // Node to start walking from. Defaults to "root".
+	Sizes []uint32 // Output sizes to render every node with. Size 0 means no size limit. Defaults to 0 only.
+	Fixtures map[string]string // Content for external symbols. If not set for a symbol, the resource entry function is used.
+	FlagCount uint32 // Number of flags used by the application in addition to the builtin flags.
+}
+
+// Snapshot holds all rendered pages of a single node at a single output size.
+type Snapshot struct {
+	Sym string
+	Size uint32
+	Pages []string
+	Error string // Error encountered while rendering, if any. Pages holds the pages rendered before the error.
+}
+
+// Name returns the golden file name for the snapshot.
+func(sn Snapshot) Name() string {
+	return fmt.Sprintf("%s_%d.golden", sn.Sym, sn.Size)
+}
+
+// String implements the String interface.
+//
+// The result is the golden file content for the snapshot.
+func(sn Snapshot) String() string {
+	var s string
+	for i, v := range sn.Pages {
+		s += fmt.Sprintf("=== page %d\n%s\n", i, v)
+	}
+	if sn.Error != "" {
+		s += fmt.Sprintf("=== error\n%s\n", sn.Error)
+	}
+	return s
+}
+
+// Snapshots renders every page of every node reachable from the root node, at every output size in the configuration.
+//
+// Each node is rendered by the vm as it would be output when first arriving at the node along the shortest path from the root node. The vm is started at the first node of the path, and is given the input leading from each node on the path to the next. Further pages are browsed to as with the ">" navigation target. Content for external symbols is taken from the configured fixtures.
+//
+// Nodes that can only be reached by CATCH cannot be navigated to, and have an error recorded in their snapshot. The _catch node is started at directly.
+//
+// Rendering errors are recorded in the snapshot. An error is only returned if the nodes could not be walked.
+func Snapshots(ctx context.Context, cfg Config, rs resource.Resource) ([]Snapshot, error) {
+	var r []Snapshot
+	if cfg.Root == "" {
+		cfg.Root = "root"
+	}
+	sizes := cfg.Sizes
+	if len(sizes) == 0 {
+		sizes = []uint32{0}
+	}
+	g, err := graph.FromResource(rs, cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Missing) > 0 {
+		return nil, fmt.Errorf("missing nodes: %s", strings.Join(g.Missing, ","))
+	}
+	frs := &fixtureResource{
+		Resource: rs,
+		fixtures: cfg.Fixtures,
+	}
+	for _, sym := range g.Nodes {
+		for _, size := range sizes {
+			sn := snapshot(ctx, cfg, frs, g, sym, size)
+			Logg.DebugCtxf(ctx, "rendered snapshot", "sym", sym, "size", size, "pages", len(sn.Pages), "err", sn.Error)
+			r = append(r, sn)
+		}
+	}
+	return r, nil
+}
+
+// snapshot renders all pages of a node at the given output size.
+func snapshot(ctx context.Context, cfg Config, rs resource.Resource, g *graph.Graph, sym string, size uint32) Snapshot {
+	sn := Snapshot{
+		Sym: sym,
+		Size: size,
+	}
+	nodes := append(g.Path(sym), sym)
+	var idx uint16
+	for {
+		s, count, err := renderPage(ctx, cfg, rs, g, nodes, size, idx)
+		if err != nil {
+			sn.Error = err.Error()
+			break
+		}
+		sn.Pages = append(sn.Pages, s)
+		idx += 1
+		if idx >= count {
+			break
+		}
+	}
+	return sn
+}
+
+// renderPage runs a new vm along the given nodes, and renders the page of the last node at the given index.
+//
+// It returns the rendered page and the total page count of the node.
+func renderPage(ctx context.Context, cfg Config, rs resource.Resource, g *graph.Graph, nodes []string, size uint32, idx uint16) (string, uint16, error) {
+	var szr *render.Sizer
+	if size > 0 {
+		szr = render.NewSizer(size)
+	}
+	st := state.NewState(cfg.FlagCount)
+	ca := cache.NewCache().WithSharedStore(cache.NewSharedStore())
+	vmi := vm.NewVm(&st, rs, ca, szr)
+
+	sym := nodes[len(nodes)-1]
+	b := vm.NewLine(nil, vm.MOVE, []string{nodes[0]}, nil, nil)
+	b, err := vmi.Run(ctx, b)
+	if err != nil {
+		return "", 0, fmt.Errorf("node %s: %v", nodes[0], err)
+	}
+	for i := 1; i < len(nodes); i++ {
+		cur, _ := st.Where()
+		if arrived(cur, nodes[i:]) {
+			continue
+		}
+		input, err := inputFor(g, cur, nodes[i])
+		if err != nil {
+			return "", 0, err
+		}
+		err = st.SetInput([]byte(input))
+		if err != nil {
+			return "", 0, err
+		}
+		b, err = vmi.Run(ctx, b)
+		if err != nil {
+			return "", 0, fmt.Errorf("node %s: %v", cur, err)
+		}
+	}
+	for i := uint16(0); i < idx; i++ {
+		b = vm.NewLine(nil, vm.MOVE, []string{">"}, nil, nil)
+		_, err = vmi.Run(ctx, b)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	cur, _ := st.Where()
+	if cur != sym {
+		return "", 0, fmt.Errorf("vm stopped at node %s", cur)
+	}
+	s, err := vmi.Render(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	cur, _ = st.Where()
+	if cur != sym {
+		return "", 0, fmt.Errorf("vm left node for %s on render", cur)
+	}
+	return s, vmi.PageCount(), nil
+}
+
+// true if the vm has already arrived at the next node, or at a node further along the path.
+//
+// This is the case when nodes are moved to without input.
+func arrived(cur string, nodes []string) bool {
+	for _, v := range nodes {
+		if v == cur {
+			return true
+		}
+	}
+	return false
+}
+
+// inputFor returns the input that makes the vm navigate from one node to the other.
+//
+// For a wildcard selector, an input not matching any other selector of the node is used.
+func inputFor(g *graph.Graph, from string, to string) (string, error) {
+	taken := make(map[string]bool)
+	var wildcard bool
+	for _, e := range g.Edges {
+		if e.From != from || e.Op != vm.INCMP {
+			continue
+		}
+		if e.Label != "*" {
+			taken[e.Label] = true
+		}
+		if e.To != to || e.Relative() {
+			continue
+		}
+		if e.Label != "*" {
+			return e.Label, nil
+		}
+		wildcard = true
+	}
+	if !wildcard {
+		return "", fmt.Errorf("no input leads from node %s to %s", from, to)
+	}
+	input := "x"
+	for taken[input] {
+		input += "x"
+	}
+	return input, nil
+}
+
+// fixtureResource serves the configured fixtures as the content of external symbols.
+type fixtureResource struct {
+	resource.Resource
+	fixtures map[string]string
+}
+
+// FuncFor implements resource.Resource.
+//
+// Configured fixtures take precedence. Otherwise the entry function of the wrapped resource is used, falling back to the symbol name itself if it fails.
+func(fr *fixtureResource) FuncFor(sym string) (resource.EntryFunc, error) {
+	return fr.get, nil
+}
+
+// content for the external symbol.
+func(fr *fixtureResource) get(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	v, ok := fr.fixtures[sym]
+	if ok {
+		return resource.Result{Content: v}, nil
+	}
+	fn, err := fr.Resource.FuncFor(sym)
+	if err == nil && fn != nil {
+		r, err := fn(ctx, sym, input)
+		if err == nil {
+			return r, nil
+		}
+	}
+	Logg.WarnCtxf(ctx, "no fixture for symbol, using symbol name", "sym", sym)
+	return resource.Result{Content: sym}, nil
+}
@@ -0,0 +1,274 @@
+package golden
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/testdata"
+	"git.defalsify.org/vise.git/vm"
+)
+
+type templateOverrideResource struct {
+	resource.Resource
+	sym string
+	tpl string
+}
+
+func(tr *templateOverrideResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	if sym == tr.sym {
+		return tr.tpl, nil
+	}
+	return tr.Resource.GetTemplate(ctx, sym)
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	dataDir, err := testdata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := resource.NewFsResource(dataDir)
+	cfg := Config{
+		Sizes: []uint32{0, 64},
+	}
+	snaps, err := Snapshots(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range snaps {
+		names = append(names, v.Name())
+	}
+	expect := "root_0.golden,root_64.golden,_catch_0.golden,_catch_64.golden,foo_0.golden,foo_64.golden,bar_0.golden,bar_64.golden,lang_0.golden,lang_64.golden,baz_0.golden,baz_64.golden,long_0.golden,long_64.golden"
+	if strings.Join(names, ",") != expect {
+		t.Fatalf("expected %s, got %s", expect, strings.Join(names, ","))
+	}
+	for _, v := range snaps {
+		if v.Error != "" {
+			t.Fatalf("%s: unexpected error: %s", v.Name(), v.Error)
+		}
+	}
+
+	// baz maps content loaded in its parent foo
+	sn := snaps[10]
+	if sn.Pages[0] != "this is baz which uses the var one in the template." {
+		t.Fatalf("unexpected baz render: %s", sn.Pages[0])
+	}
+
+	sn = snaps[12]
+	if len(sn.Pages) != 1 {
+		t.Fatalf("expected unsized render to have 1 page, got %d", len(sn.Pages))
+	}
+	sn = snaps[13]
+	if len(sn.Pages) < 2 {
+		t.Fatalf("expected sized render to be paged, got %d pages", len(sn.Pages))
+	}
+	for i, v := range sn.Pages {
+		if len(v) > 64 {
+			t.Fatalf("page %d exceeds output size: %d", i, len(v))
+		}
+	}
+}
+
+func TestSnapshotsFixture(t *testing.T) {
+	ctx := context.Background()
+	dataDir, err := testdata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := resource.NewFsResource(dataDir)
+	cfg := Config{
+		Root: "foo",
+		Sizes: []uint32{0, 32},
+		Fixtures: map[string]string{
+			"inky": "xyzzy",
+		},
+	}
+	snaps, err := Snapshots(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range snaps {
+		if v.Sym == "baz" && v.Size == 0 {
+			if v.Error != "" {
+				t.Fatalf("unexpected error: %s", v.Error)
+			}
+			if !strings.Contains(v.Pages[0], "xyzzy") {
+				t.Fatalf("expected fixture in output, got: %s", v.Pages[0])
+			}
+		}
+		if v.Sym == "foo" && v.Size == 32 {
+			if v.Error == "" {
+				t.Fatalf("expected capacity error, got pages: %v", v.Pages)
+			}
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dataDir, err := testdata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := resource.NewFsResource(dataDir)
+	cfg := Config{
+		Sizes: []uint32{0, 64},
+	}
+	snaps, err := Snapshots(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Compare(dir, snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != len(snaps) {
+		t.Fatalf("expected %d missing, got %d", len(snaps), len(r))
+	}
+	for _, v := range r {
+		if !v.Missing {
+			t.Fatalf("expected missing, got %s", v)
+		}
+	}
+
+	err = Write(dir, snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = Compare(dir, snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 0 {
+		t.Fatalf("expected no mismatches after write, got %v", r)
+	}
+
+	ors := &templateOverrideResource{
+		Resource: rs,
+		sym: "bar",
+		tpl: "this is not bar",
+	}
+	snaps, err = Snapshots(ctx, cfg, ors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = Compare(dir, snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("expected 2 mismatches, got %v", r)
+	}
+	expect := `  === page 0
+- this is bar - any input will return to top
++ this is not bar
+  `
+	if r[0].Name != "bar_0.golden" || r[0].Diff != expect {
+		t.Fatalf("unexpected mismatch: %s", r[0])
+	}
+
+	cfg.Sizes = []uint32{0}
+	snaps, err = Snapshots(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = Compare(dir, snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 7 {
+		t.Fatalf("expected 7 stale, got %v", r)
+	}
+	for _, v := range r {
+		if !v.Stale {
+			t.Fatalf("expected stale, got %s", v)
+		}
+	}
+}
+
+func TestSnapshotsNavigate(t *testing.T) {
+	ctx := context.Background()
+	rs := resource.NewMemResource()
+	b := vm.NewLine(nil, vm.CATCH, []string{"trap"}, []byte{0x08}, []uint8{1})
+	b = vm.NewLine(b, vm.MOUT, []string{"1", "go"}, nil, nil)
+	b = vm.NewLine(b, vm.HALT, nil, nil, nil)
+	b = vm.NewLine(b, vm.INCMP, []string{"_catch", "1"}, nil, nil)
+	b = vm.NewLine(b, vm.INCMP, []string{"mid", "*"}, nil, nil)
+	rs.AddBytecode("root", b)
+	rs.AddTemplate("root", "root")
+	b = vm.NewLine(nil, vm.LOAD, []string{"inky"}, []byte{0x00}, nil)
+	b = vm.NewLine(b, vm.MOVE, []string{"last"}, nil, nil)
+	rs.AddBytecode("mid", b)
+	b = vm.NewLine(nil, vm.MAP, []string{"inky"}, nil, nil)
+	b = vm.NewLine(b, vm.HALT, nil, nil, nil)
+	rs.AddBytecode("last", b)
+	rs.AddTemplate("last", "inky is {{.inky}}")
+	b = vm.NewLine(nil, vm.HALT, nil, nil, nil)
+	rs.AddBytecode("trap", b)
+	rs.AddTemplate("trap", "trapped")
+	rs.AddBytecode("_catch", b)
+	rs.AddTemplate("_catch", "caught")
+
+	cfg := Config{
+		Fixtures: map[string]string{
+			"inky": "pinky",
+		},
+		FlagCount: 1,
+	}
+	snaps, err := Snapshots(ctx, cfg, &rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := make(map[string]Snapshot)
+	for _, v := range snaps {
+		r[v.Sym] = v
+	}
+
+	// wildcard input into a node moving on without input
+	sn := r["last"]
+	if sn.Error != "" {
+		t.Fatalf("unexpected error: %s", sn.Error)
+	}
+	if sn.Pages[0] != "inky is pinky" {
+		t.Fatalf("unexpected render: %s", sn.Pages[0])
+	}
+	if r["mid"].Error == "" {
+		t.Fatalf("expected error for node never halted at")
+	}
+	if r["trap"].Error == "" {
+		t.Fatalf("expected error for node only reachable by catch")
+	}
+}
+
+func TestSnapshotsMemResource(t *testing.T) {
+	ctx := context.Background()
+	rs := resource.NewMemResource()
+	b := vm.NewLine(nil, vm.MOUT, []string{"1", "go"}, nil, nil)
+	b = vm.NewLine(b, vm.HALT, nil, nil, nil)
+	b = vm.NewLine(b, vm.INCMP, []string{"next", "1"}, nil, nil)
+	rs.AddBytecode("root", b)
+	rs.AddTemplate("root", "root")
+	b = vm.NewLine(nil, vm.HALT, nil, nil, nil)
+	rs.AddBytecode("next", b)
+	rs.AddTemplate("next", "next")
+
+	snaps, err := Snapshots(ctx, Config{}, &rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snaps))
+	}
+	for _, v := range snaps {
+		if v.Error != "" {
+			t.Fatalf("%s: unexpected error: %s", v.Name(), v.Error)
+		}
+	}
+	if snaps[1].Pages[0] != "next" {
+		t.Fatalf("unexpected render: %s", snaps[1].Pages[0])
+	}
+}
@@ -0,0 +1,31 @@
+// Package goldentest checks golden snapshots from Go tests.
+//
+// It is kept apart from package golden, so that the testing package is only linked into test binaries.
+package goldentest
+
+import (
+	"testing"
+
+	"git.defalsify.org/vise.git/golden"
+)
+
+// Check compares the snapshots against the golden files in the given directory, and fails the test for every mismatch.
+//
+// If update is set, the golden files are written instead.
+func Check(t testing.TB, dir string, snaps []golden.Snapshot, update bool) {
+	t.Helper()
+	if update {
+		err := golden.Write(dir, snaps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	r, err := golden.Compare(dir, snaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range r {
+		t.Error(v)
+	}
+}
@@ -0,0 +1,26 @@
+package goldentest
+
+import (
+	"context"
+	"testing"
+
+	"git.defalsify.org/vise.git/golden"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/testdata"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dataDir, err := testdata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := resource.NewFsResource(dataDir)
+	snaps, err := golden.Snapshots(ctx, golden.Config{}, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Check(t, dir, snaps, true)
+	Check(t, dir, snaps, false)
+}
@@ -0,0 +1,13 @@
+package golden
+
+import (
+	"git.defalsify.org/vise.git/logging"
+)
+
+var (
+	Logg logging.Logger = logging.NewVanilla().WithDomain("golden")
+)
+
+func init() {
+	logging.Register("golden", &Logg)
+}
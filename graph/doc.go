@@ -0,0 +1,2 @@
+// Package graph extracts the navigation graph of a vise application from its bytecode, and exports it for visualization.
+package graph
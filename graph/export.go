@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"git.defalsify.org/vise.git/vm"
+)
+
+// WriteDot writes the graph in Graphviz DOT format.
+//
+// The root node is drawn with a double circle. Edges to relative targets are dashed, and CATCH edges are red.
+func(g *Graph) WriteDot(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph vise {\n")
+	for _, v := range g.Nodes {
+		if v == g.Root {
+			fmt.Fprintf(&b, "\t%s [shape=doublecircle];\n", dotQuote(v))
+		} else {
+			fmt.Fprintf(&b, "\t%s;\n", dotQuote(v))
+		}
+	}
+	for _, v := range g.Missing {
+		fmt.Fprintf(&b, "\t%s [style=dashed];\n", dotQuote(v))
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Label != "" {
+			attrs = append(attrs, "label=" + dotQuote(e.Label))
+		}
+		if e.Relative() {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.Op == vm.CATCH {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "\t%s -> %s", dotQuote(e.From), dotQuote(e.To))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+//
+// The root node is drawn as a stadium. Edges to relative targets are dotted, and CATCH edges are thick.
+func(g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	ids := make(map[string]string)
+	b.WriteString("flowchart TD\n")
+	for i, v := range append(append([]string{}, g.Nodes...), g.Missing...) {
+		id := fmt.Sprintf("n%d", i)
+		ids[v] = id
+		if v == g.Root {
+			fmt.Fprintf(&b, "\t%s([%s])\n", id, mermaidQuote(v))
+		} else {
+			fmt.Fprintf(&b, "\t%s[%s]\n", id, mermaidQuote(v))
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Relative() {
+			arrow = "-.->"
+		} else if e.Op == vm.CATCH {
+			arrow = "==>"
+		}
+		fmt.Fprintf(&b, "\t%s %s", ids[e.From], arrow)
+		if e.Label != "" {
+			fmt.Fprintf(&b, "|%s|", mermaidQuote(e.Label))
+		}
+		fmt.Fprintf(&b, " %s\n", ids[e.To])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoted DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return "\"" + strings.ReplaceAll(s, "\"", "\\\"") + "\""
+}
+
+// quoted Mermaid text.
+func mermaidQuote(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "#quot;") + "\""
+}
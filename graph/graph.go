@@ -0,0 +1,222 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"git.defalsify.org/vise.git/asm"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/vm"
+)
+
+// CodeFunc retrieves the bytecode for a node symbol.
+type CodeFunc func(sym string) ([]byte, error)
+
+// Edge is a possible navigation from one node to another.
+type Edge struct {
+	From string
+	To string // Node navigated to. For relative targets this is the resolved node.
+	Target string // Target as defined in the bytecode.
+	Op vm.Opcode // Instruction defining the edge; MOVE, INCMP or CATCH.
+	Label string // Input selector (and menu title) for INCMP, flag condition for CATCH.
+}
+
+// Relative returns true if the edge target is relative to the navigation state.
+func(e Edge) Relative() bool {
+	switch e.Target {
+	case "_", "^", "<", ">", ".":
+		return true
+	}
+	return false
+}
+
+// Graph is the navigation graph of all nodes reachable from a root node.
+type Graph struct {
+	Root string
+	Nodes []string // Reachable nodes, in order of discovery.
+	Edges []Edge
+	Missing []string // Targets for which no bytecode exists.
+	parents map[string]string
+}
+
+// FromResource builds the navigation graph from the bytecode of the resource.
+func FromResource(rs resource.Resource, root string) (*Graph, error) {
+	return Build(root, rs.GetCode)
+}
+
+// FromSource builds the navigation graph from the assembly source files (<sym>.vis) in the given directory.
+func FromSource(dir string, root string) (*Graph, error) {
+	return Build(root, func(sym string) ([]byte, error) {
+		buf := bytes.NewBuffer(nil)
+		_, err := asm.ParseFile(path.Join(dir, sym + ".vis"), buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// Build walks all nodes reachable from the root node, and builds the navigation graph from their bytecode.
+//
+// Targets of MOVE, INCMP and CATCH are followed. The _catch node is included if it exists, as the vm will move there on invalid input.
+//
+// Targets for which the code function returns an error matching fs.ErrNotExist, as the resources of the resource package do for unknown bytecode, are listed in Missing. Any other error, or bytecode that cannot be parsed, fails the build.
+func Build(root string, fn CodeFunc) (*Graph, error) {
+	g := &Graph{
+		Root: root,
+		parents: make(map[string]string),
+	}
+	seen := make(map[string]bool)
+	q := []string{root}
+	if _, err := fn("_catch"); !errors.Is(err, fs.ErrNotExist) && root != "_catch" {
+		q = append(q, "_catch")
+	}
+	for _, v := range q {
+		seen[v] = true
+	}
+	for len(q) > 0 {
+		sym := q[0]
+		q = q[1:]
+		code, err := fn(sym)
+		if errors.Is(err, fs.ErrNotExist) {
+			Logg.Debugf("no code for node", "sym", sym)
+			g.Missing = append(g.Missing, sym)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("node %s: %v", sym, err)
+		}
+		g.Nodes = append(g.Nodes, sym)
+		edges, err := edgesOf(sym, code)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %v", sym, err)
+		}
+		for _, v := range edges {
+			g.Edges = append(g.Edges, v)
+			if v.Relative() || seen[v.To] {
+				continue
+			}
+			seen[v.To] = true
+			g.parents[v.To] = sym
+			q = append(q, v.To)
+		}
+	}
+	g.resolve()
+	return g, nil
+}
+
+// Path returns the nodes leading up to the given node along the shortest path from the root node, starting with the root node.
+//
+// The path of the root node is empty.
+func(g *Graph) Path(sym string) []string {
+	var r []string
+	for p := g.parents[sym]; p != ""; p = g.parents[p] {
+		r = append([]string{p}, r...)
+	}
+	return r
+}
+
+// resolve the destination node of relative targets.
+//
+// Browse and same node targets point back to the node itself, top to the root node, and back to every node with an absolute edge to the node.
+func(g *Graph) resolve() {
+	var r []Edge
+	for _, e := range g.Edges {
+		switch e.Target {
+		case "<", ">", ".":
+			e.To = e.From
+		case "^":
+			e.To = g.Root
+		case "_":
+			for _, v := range g.sources(e.From) {
+				ee := e
+				ee.To = v
+				r = append(r, ee)
+			}
+			continue
+		}
+		r = append(r, e)
+	}
+	g.Edges = r
+}
+
+// nodes with an absolute edge to the given node.
+func(g *Graph) sources(sym string) []string {
+	var r []string
+	seen := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.Relative() || e.To != sym || seen[e.From] {
+			continue
+		}
+		seen[e.From] = true
+		r = append(r, e.From)
+	}
+	return r
+}
+
+// edgesOf extracts the edges of a node from its bytecode.
+func edgesOf(sym string, b []byte) ([]Edge, error) {
+	var r []Edge
+	titles := make(map[string]string)
+	for len(b) > 0 {
+		var e Edge
+		op, bb, err := vm.ParseOp(b)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case vm.MOVE:
+			e.Target, bb, err = vm.ParseMove(bb)
+		case vm.INCMP:
+			e.Target, e.Label, bb, err = vm.ParseInCmp(bb)
+		case vm.CATCH:
+			var sig uint32
+			var mode bool
+			e.Target, sig, mode, bb, err = vm.ParseCatch(bb)
+			if mode {
+				e.Label = fmt.Sprintf("flag %v", sig)
+			} else {
+				e.Label = fmt.Sprintf("not flag %v", sig)
+			}
+		case vm.MOUT:
+			var selector string
+			var title string
+			title, selector, bb, err = vm.ParseMOut(bb)
+			titles[selector] = title
+		case vm.MNEXT, vm.MPREV:
+			var selector string
+			var title string
+			if op == vm.MNEXT {
+				title, selector, bb, err = vm.ParseMNext(bb)
+			} else {
+				title, selector, bb, err = vm.ParseMPrev(bb)
+			}
+			titles[selector] = title
+		default:
+			_, bb, err = vm.ParseInstruction(b)
+		}
+		if err != nil {
+			return nil, err
+		}
+		b = bb
+		if e.Target == "" {
+			continue
+		}
+		e.From = sym
+		e.To = e.Target
+		e.Op = op
+		r = append(r, e)
+	}
+	for i, e := range r {
+		if e.Op != vm.INCMP {
+			continue
+		}
+		title, ok := titles[e.Label]
+		if ok {
+			r[i].Label += " " + title
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/asm"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/testdata"
+	"git.defalsify.org/vise.git/vm"
+)
+
+var testSources = map[string]string{
+	"root": `LOAD foo 0
+CATCH nope 9 1
+MOUT foo 1
+HALT
+INCMP foo 1
+`,
+	"foo": `MAP foo
+MNEXT next 11
+MOUT back 0
+HALT
+INCMP > 11
+INCMP _ 0
+INCMP ^ *
+`,
+}
+
+func TestGraphResource(t *testing.T) {
+	dataDir, err := testdata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := resource.NewFsResource(dataDir)
+	g, err := FromResource(rs, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "root,_catch,foo,bar,lang,baz,long"
+	if strings.Join(g.Nodes, ",") != expect {
+		t.Fatalf("expected nodes %s, got %s", expect, strings.Join(g.Nodes, ","))
+	}
+	if len(g.Missing) != 0 {
+		t.Fatalf("expected no missing nodes, got %v", g.Missing)
+	}
+	for _, e := range g.Edges {
+		if e.From == "baz" {
+			t.Fatalf("unexpected edge from baz: %v", e)
+		}
+		if e.From == "bar" && e.Target == "^" && e.To != "root" {
+			t.Fatalf("expected top to resolve to root, got %v", e)
+		}
+	}
+
+	p := g.Path("baz")
+	if strings.Join(p, ",") != "root,foo" {
+		t.Fatalf("expected path root,foo, got %v", p)
+	}
+	if len(g.Path("root")) != 0 {
+		t.Fatalf("expected empty root path")
+	}
+}
+
+func TestGraphMemResource(t *testing.T) {
+	rs := resource.NewMemResource()
+	for k, v := range testSources {
+		b := bytes.NewBuffer(nil)
+		_, err := asm.Parse(v, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.AddBytecode(k, b.Bytes())
+	}
+	g, err := FromResource(&rs, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(g.Nodes, ",") != "root,foo" {
+		t.Fatalf("expected nodes root,foo, got %v", g.Nodes)
+	}
+	if strings.Join(g.Missing, ",") != "nope" {
+		t.Fatalf("expected missing nope, got %v", g.Missing)
+	}
+}
+
+func TestGraphSource(t *testing.T) {
+	dir := t.TempDir()
+	for k, v := range testSources {
+		err := os.WriteFile(path.Join(dir, k + ".vis"), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, err := FromSource(dir, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(g.Nodes, ",") != "root,foo" {
+		t.Fatalf("expected nodes root,foo, got %v", g.Nodes)
+	}
+	if strings.Join(g.Missing, ",") != "nope" {
+		t.Fatalf("expected missing nope, got %v", g.Missing)
+	}
+	var back *Edge
+	for i, e := range g.Edges {
+		if e.Target == "_" {
+			back = &g.Edges[i]
+		}
+		if e.Op == vm.INCMP && e.From == "root" && e.Label != "1 foo" {
+			t.Fatalf("unexpected label: %s", e.Label)
+		}
+	}
+	if back == nil || back.From != "foo" || back.To != "root" {
+		t.Fatalf("expected back edge foo -> root, got %v", back)
+	}
+
+	w := bytes.NewBuffer(nil)
+	err = g.WriteDot(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `digraph vise {
+	"root" [shape=doublecircle];
+	"foo";
+	"nope" [style=dashed];
+	"root" -> "nope" [label="flag 9", color=red];
+	"root" -> "foo" [label="1 foo"];
+	"foo" -> "foo" [label="11 next", style=dashed];
+	"foo" -> "root" [label="0 back", style=dashed];
+	"foo" -> "root" [label="*", style=dashed];
+}
+`
+	if w.String() != expect {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expect, w)
+	}
+
+	w.Reset()
+	err = g.WriteMermaid(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = `flowchart TD
+	n0(["root"])
+	n1["foo"]
+	n2["nope"]
+	n0 ==>|"flag 9"| n2
+	n0 -->|"1 foo"| n1
+	n1 -.->|"11 next"| n1
+	n1 -.->|"0 back"| n0
+	n1 -.->|"*"| n0
+`
+	if w.String() != expect {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expect, w)
+	}
+}
@@ -0,0 +1,13 @@
+package graph
+
+import (
+	"git.defalsify.org/vise.git/logging"
+)
+
+var (
+	Logg logging.Logger = logging.NewVanilla().WithDomain("graph")
+)
+
+func init() {
+	logging.Register("graph", &Logg)
+}
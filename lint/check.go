@@ -0,0 +1,182 @@
+package lint
+
+import (
+	"git.defalsify.org/vise.git/asm"
+	"git.defalsify.org/vise.git/graph"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/state"
+	"git.defalsify.org/vise.git/vm"
+)
+
+// navigation targets without bytecode.
+func(lt *linter) checkTargets(nd *node, g *graph.Graph) {
+	missing := make(map[string]bool)
+	for _, v := range g.Missing {
+		missing[v] = true
+	}
+	for _, in := range nd.code {
+		switch in.op {
+		case vm.MOVE, vm.INCMP, vm.CATCH:
+			if missing[in.sym] {
+				lt.add(CHECK_MISSING_NODE, nd, in.pos, "%s target '%s' has no bytecode", vm.OpcodeString[in.op], in.sym)
+			}
+		}
+	}
+}
+
+// template of a node that is rendered.
+//
+// Nodes that unconditionally MOVE before halting are never rendered.
+func(lt *linter) checkTemplate(nd *node) {
+	for _, in := range nd.code {
+		if in.op == vm.MOVE {
+			return
+		}
+		if in.op == vm.HALT {
+			break
+		}
+	}
+	_, err := lt.rs.GetTemplate(lt.ctx, nd.sym)
+	if err != nil {
+		lt.add(CHECK_MISSING_TEMPLATE, nd, asm.Position{}, "no template for node: %v", err)
+	}
+}
+
+// symbols loaded in the node or any of the given nodes, and their size limits.
+func(lt *linter) loaded(nd *node, syms []string) map[string]uint32 {
+	r := make(map[string]uint32)
+	nds := []*node{nd}
+	for _, v := range syms {
+		nds = append(nds, lt.nodes[v])
+	}
+	for _, v := range nds {
+		for _, in := range v.code {
+			if in.op != vm.LOAD {
+				continue
+			}
+			sz, ok := r[in.sym]
+			if !ok || in.size < sz {
+				r[in.sym] = in.size
+			}
+		}
+	}
+	return r
+}
+
+// MAP and RELOAD of symbols not loaded.
+func(lt *linter) checkMap(nd *node, ancestors []string) {
+	loaded := lt.loaded(nd, ancestors)
+	for _, in := range nd.code {
+		if in.op != vm.MAP && in.op != vm.RELOAD {
+			continue
+		}
+		_, ok := loaded[in.sym]
+		if !ok {
+			lt.add(CHECK_MAP_UNLOADED, nd, in.pos, "%s of symbol '%s' which is never loaded", vm.OpcodeString[in.op], in.sym)
+		}
+	}
+}
+
+// multiple sinks, and sinks combined with menu sink.
+func(lt *linter) checkSink(nd *node, ancestors []string) {
+	var sink string
+	var menuSink bool
+	loaded := lt.loaded(nd, ancestors)
+	for _, in := range nd.code {
+		if in.op == vm.MSINK {
+			menuSink = true
+			if sink != "" {
+				lt.add(CHECK_MENU_SINK, nd, in.pos, "MSINK combined with sink symbol '%s'", sink)
+			}
+			continue
+		}
+		if in.op != vm.MAP {
+			continue
+		}
+		sz, ok := loaded[in.sym]
+		if !ok || sz > 0 || in.sym == sink {
+			continue
+		}
+		if sink != "" {
+			lt.add(CHECK_MULTIPLE_SINK, nd, in.pos, "sink symbol '%s' mapped in addition to sink symbol '%s'", in.sym, sink)
+			continue
+		}
+		sink = in.sym
+		if menuSink {
+			lt.add(CHECK_MENU_SINK, nd, in.pos, "sink symbol '%s' combined with MSINK", sink)
+		}
+	}
+}
+
+// menu items without input matches, and duplicate selectors.
+func(lt *linter) checkMenu(nd *node) {
+	matches := make(map[string]bool)
+	items := make(map[string]bool)
+	for _, in := range nd.code {
+		if in.op != vm.INCMP {
+			continue
+		}
+		if matches[in.selector] {
+			lt.add(CHECK_DUPLICATE_SELECTOR, nd, in.pos, "INCMP selector '%s' already used", in.selector)
+		}
+		matches[in.selector] = true
+	}
+	for _, in := range nd.code {
+		switch in.op {
+		case vm.MOUT, vm.MNEXT, vm.MPREV:
+		default:
+			continue
+		}
+		if items[in.selector] {
+			lt.add(CHECK_DUPLICATE_SELECTOR, nd, in.pos, "menu selector '%s' already used", in.selector)
+		}
+		items[in.selector] = true
+		if !matches[in.selector] {
+			lt.add(CHECK_UNMATCHED_MENU, nd, in.pos, "no INCMP for menu selector '%s'", in.selector)
+		}
+	}
+}
+
+// LOAD and RELOAD of symbols without entry function.
+func(lt *linter) checkFunctions(nd *node) {
+	for _, in := range nd.code {
+		if in.op != vm.LOAD && in.op != vm.RELOAD {
+			continue
+		}
+		fn, err := lt.rs.FuncFor(in.sym)
+		if err != nil || fn == nil {
+			lt.add(CHECK_MISSING_FUNCTION, nd, in.pos, "no entry function for symbol '%s'", in.sym)
+			continue
+		}
+		fd, ok := lt.rs.(resource.FuncDescriber)
+		if !ok {
+			continue
+		}
+		info, err := fd.DescribeFunc(in.sym)
+		if err != nil {
+			continue
+		}
+		lt.checkFuncFlags(nd, in, info)
+		if in.op != vm.LOAD || in.size == 0 {
+			continue
+		}
+		if info.MaxSize > in.size {
+			lt.add(CHECK_LOAD_SIZE, nd, in.pos, "LOAD of symbol '%s' limited to %d bytes, but entry function may return %d", in.sym, in.size, info.MaxSize)
+		}
+	}
+}
+
+// flags declared for the entry function that it cannot change.
+//
+// Builtin flags other than the language flag are ignored by the vm, and flags beyond the flag count of the application do not exist in the state.
+func(lt *linter) checkFuncFlags(nd *node, in instruction, info resource.FuncInfo) {
+	for _, v := range info.Flags {
+		if !state.IsWriteableFlag(v) {
+			lt.add(CHECK_FUNC_FLAGS, nd, in.pos, "entry function for symbol '%s' may change builtin flag %d, which is not writeable", in.sym, v)
+			continue
+		}
+		if lt.cfg.FlagCount > 0 && v >= state.FLAG_USERSTART + lt.cfg.FlagCount {
+			lt.add(CHECK_FUNC_FLAGS, nd, in.pos, "entry function for symbol '%s' may change flag %d, beyond the %d flags of the application", in.sym, v, lt.cfg.FlagCount)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+// Package lint statically checks the bytecode, templates and entry functions of a vise application for mistakes that would otherwise surface as runtime errors.
+package lint
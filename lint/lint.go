@@ -0,0 +1,232 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"git.defalsify.org/vise.git/asm"
+	"git.defalsify.org/vise.git/graph"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/vm"
+)
+
+const (
+	CHECK_MISSING_NODE = "missing-node" // Navigation target has no bytecode.
+	CHECK_MISSING_TEMPLATE = "missing-template" // Rendered node has no template.
+	CHECK_MAP_UNLOADED = "map-unloaded" // MAP or RELOAD of a symbol not loaded in the node or any node leading to it.
+	CHECK_MULTIPLE_SINK = "multiple-sink" // More than one sink symbol mapped in the same node.
+	CHECK_MENU_SINK = "menu-sink" // MSINK combined with a mapped sink symbol.
+	CHECK_UNMATCHED_MENU = "unmatched-menu" // Menu item without an INCMP for its selector.
+	CHECK_DUPLICATE_SELECTOR = "duplicate-selector" // Selector used more than once for menu items or input matches.
+	CHECK_MISSING_FUNCTION = "missing-function" // LOAD or RELOAD of a symbol without an entry function.
+	CHECK_LOAD_SIZE = "load-size" // LOAD size limit below the maximum size declared for the entry function.
+	CHECK_FUNC_FLAGS = "func-flags" // Entry function declared to change a flag outside the writeable range.
+)
+
+// Config defines the scope of the checks.
+type Config struct {
+	Root string // Node to start from. Defaults to "root".
+	SourceDir string // Directory of assembly sources (<sym>.vis). If set, issues are reported with source file and line.
+	Skip []string // Checks not to perform.
+	FlagCount uint32 // Number of flags used by the application in addition to the builtin flags. If set, entry functions changing flags beyond them are reported.
+}
+
+// Issue is a single problem found by a check.
+type Issue struct {
+	Check string
+	Sym string // Node the issue was found in.
+	File string // Source file of the instruction causing the issue, or of the node if the instruction is not known. Empty if not available.
+	Line int // Source line of the instruction causing the issue. Zero if not available.
+	Msg string
+}
+
+// String implements the String interface.
+func(is Issue) String() string {
+	loc := is.Sym + ".bin"
+	if is.File != "" {
+		loc = is.File
+		if is.Line > 0 {
+			loc += fmt.Sprintf(":%d", is.Line)
+		}
+	}
+	return fmt.Sprintf("%s: %s [%s]", loc, is.Msg, is.Check)
+}
+
+// decoded instruction with the arguments relevant to the checks.
+type instruction struct {
+	op vm.Opcode
+	sym string
+	selector string
+	size uint32
+	pos asm.Position // Source position, if available.
+}
+
+// bytecode and decoded instructions of a single node.
+type node struct {
+	sym string
+	file string
+	code []instruction
+}
+
+// state of a single lint run.
+type linter struct {
+	ctx context.Context
+	cfg Config
+	rs resource.Resource
+	nodes map[string]*node
+	issues []Issue
+}
+
+// Lint checks all nodes reachable from the root node.
+//
+// An error is only returned if the nodes could not be walked or decoded.
+func Lint(ctx context.Context, cfg Config, rs resource.Resource) ([]Issue, error) {
+	if cfg.Root == "" {
+		cfg.Root = "root"
+	}
+	g, err := graph.FromResource(rs, cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Nodes) == 0 || g.Nodes[0] != cfg.Root {
+		return nil, fmt.Errorf("no bytecode for root node '%s'", cfg.Root)
+	}
+	lt := &linter{
+		ctx: ctx,
+		cfg: cfg,
+		rs: rs,
+		nodes: make(map[string]*node),
+	}
+	for _, sym := range g.Nodes {
+		nd, err := lt.decode(sym)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %v", sym, err)
+		}
+		lt.nodes[sym] = nd
+	}
+	for _, sym := range g.Nodes {
+		nd := lt.nodes[sym]
+		lt.checkTargets(nd, g)
+		lt.checkTemplate(nd)
+		lt.checkMap(nd, ancestors(g, sym))
+		lt.checkSink(nd, ancestors(g, sym))
+		lt.checkMenu(nd)
+		lt.checkFunctions(nd)
+	}
+	return lt.issues, nil
+}
+
+// add an issue, unless the check is skipped.
+//
+// The issue is attributed to the source position, or to the source file of the node if the position is not known.
+func(lt *linter) add(check string, nd *node, pos asm.Position, msg string, args ...any) {
+	for _, v := range lt.cfg.Skip {
+		if v == check {
+			return
+		}
+	}
+	is := Issue{
+		Check: check,
+		Sym: nd.sym,
+		File: pos.File,
+		Line: pos.Line,
+		Msg: fmt.Sprintf(msg, args...),
+	}
+	if is.File == "" {
+		is.File = nd.file
+	}
+	Logg.DebugCtxf(lt.ctx, "lint issue", "issue", is)
+	lt.issues = append(lt.issues, is)
+}
+
+// decode the bytecode of the node, and attach source lines if available.
+func(lt *linter) decode(sym string) (*node, error) {
+	b, err := lt.rs.GetCode(sym)
+	if err != nil {
+		return nil, err
+	}
+	nd := &node{
+		sym: sym,
+	}
+	for len(b) > 0 {
+		var in instruction
+		op, bb, err := vm.ParseOp(b)
+		if err != nil {
+			return nil, err
+		}
+		in.op = op
+		switch op {
+		case vm.LOAD:
+			in.sym, in.size, bb, err = vm.ParseLoad(bb)
+		case vm.RELOAD:
+			in.sym, bb, err = vm.ParseReload(bb)
+		case vm.MAP:
+			in.sym, bb, err = vm.ParseMap(bb)
+		case vm.MOVE:
+			in.sym, bb, err = vm.ParseMove(bb)
+		case vm.INCMP:
+			in.sym, in.selector, bb, err = vm.ParseInCmp(bb)
+		case vm.CATCH:
+			in.sym, _, _, bb, err = vm.ParseCatch(bb)
+		case vm.MOUT:
+			in.sym, in.selector, bb, err = vm.ParseMOut(bb)
+		case vm.MNEXT:
+			in.sym, in.selector, bb, err = vm.ParseMNext(bb)
+		case vm.MPREV:
+			in.sym, in.selector, bb, err = vm.ParseMPrev(bb)
+		default:
+			_, bb, err = vm.ParseInstruction(b)
+		}
+		if err != nil {
+			return nil, err
+		}
+		b = bb
+		nd.code = append(nd.code, in)
+	}
+	if lt.cfg.SourceDir == "" {
+		return nd, nil
+	}
+	fp := path.Join(lt.cfg.SourceDir, sym + ".vis")
+	_, err = os.Stat(fp)
+	if err != nil {
+		return nd, nil
+	}
+	nd.file = fp
+	pos, err := asm.Positions(fp)
+	if err != nil {
+		Logg.WarnCtxf(lt.ctx, "source could not be assembled, omitting line numbers", "sym", sym, "err", err)
+		return nd, nil
+	}
+	if len(pos) != len(nd.code) {
+		Logg.WarnCtxf(lt.ctx, "source does not match bytecode, omitting line numbers", "sym", sym, "source", len(pos), "bytecode", len(nd.code))
+		return nd, nil
+	}
+	for i, v := range pos {
+		nd.code[i].pos = v
+	}
+	return nd, nil
+}
+
+// nodes with an absolute navigation path to the given node.
+//
+// Content loaded in these nodes may still be in cache when the node is executed.
+func ancestors(g *graph.Graph, sym string) []string {
+	var r []string
+	seen := map[string]bool{sym: true}
+	q := []string{sym}
+	for len(q) > 0 {
+		cur := q[0]
+		q = q[1:]
+		for _, e := range g.Edges {
+			if e.Relative() || e.To != cur || seen[e.From] {
+				continue
+			}
+			seen[e.From] = true
+			r = append(r, e.From)
+			q = append(q, e.From)
+		}
+	}
+	return r
+}
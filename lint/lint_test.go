@@ -0,0 +1,276 @@
+package lint
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"git.defalsify.org/vise.git/asm"
+	"git.defalsify.org/vise.git/resource"
+)
+
+var testSources = map[string]string{
+	"root": `LOAD foo 0
+LOAD bar 0
+MAP foo
+MAP bar
+MAP baz
+MOUT one 1
+MOUT two 2
+MOUT uno 1
+HALT
+INCMP one 1
+INCMP nope 3
+INCMP sink 3
+`,
+	"one": `LOAD nofunc 10
+MOVE _
+`,
+	"sink": `MAP foo
+MSINK
+HALT
+`,
+}
+
+var testFiles = map[string]string{
+	"root": "hello",
+	"foo.txt": "foo",
+	"bar.txt": "bar",
+}
+
+func writeTestData(t *testing.T) string {
+	dir := t.TempDir()
+	for k, v := range testSources {
+		err := os.WriteFile(path.Join(dir, k + ".vis"), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := bytes.NewBuffer(nil)
+		_, err = asm.Parse(v, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path.Join(dir, k + ".bin"), b.Bytes(), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for k, v := range testFiles {
+		err := os.WriteFile(path.Join(dir, k), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLint(t *testing.T) {
+	ctx := context.Background()
+	dir := writeTestData(t)
+	rs := resource.NewFsResource(dir)
+	cfg := Config{
+		SourceDir: dir,
+	}
+	r, err := Lint(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"root.vis:11: INCMP target 'nope' has no bytecode [missing-node]",
+		"root.vis:5: MAP of symbol 'baz' which is never loaded [map-unloaded]",
+		"root.vis:4: sink symbol 'bar' mapped in addition to sink symbol 'foo' [multiple-sink]",
+		"root.vis:12: INCMP selector '3' already used [duplicate-selector]",
+		"root.vis:7: no INCMP for menu selector '2' [unmatched-menu]",
+		"root.vis:8: menu selector '1' already used [duplicate-selector]",
+		"one.vis:1: no entry function for symbol 'nofunc' [missing-function]",
+		"sink.vis: no template for node: failed getting template for sym 'sink': open sink: no such file or directory [missing-template]",
+		"sink.vis:2: MSINK combined with sink symbol 'foo' [menu-sink]",
+	}
+	if len(r) != len(expect) {
+		t.Fatalf("expected %d issues, got %d: %v", len(expect), len(r), r)
+	}
+	for i, v := range r {
+		s := v.String()[len(dir)+1:]
+		if s != expect[i] {
+			t.Fatalf("issue %d: expected\n%s\ngot\n%s", i, expect[i], s)
+		}
+	}
+
+	cfg = Config{
+		Skip: []string{CHECK_DUPLICATE_SELECTOR, CHECK_MISSING_TEMPLATE},
+	}
+	r, err = Lint(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != len(expect) - 3 {
+		t.Fatalf("expected %d issues, got %d: %v", len(expect) - 3, len(r), r)
+	}
+	if r[0].String() != "root.bin: INCMP target 'nope' has no bytecode [missing-node]" {
+		t.Fatalf("unexpected issue without source: %s", r[0])
+	}
+
+	rs.AddLocalFunc("nofunc", func(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+		return resource.Result{}, nil
+	})
+	r, err = Lint(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range r {
+		if v.Check == CHECK_MISSING_FUNCTION {
+			t.Fatalf("unexpected issue: %s", v)
+		}
+	}
+
+	fns := resource.NewFuncRegistry()
+	fns.MustRegister(resource.FuncInfo{
+		Sym: "nofunc",
+		MaxSize: 20,
+		Func: func(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+			return resource.Result{}, nil
+		},
+	})
+	rs.WithFuncRegistry(fns)
+	r, err = Lint(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c int
+	for _, v := range r {
+		if v.Check == CHECK_LOAD_SIZE {
+			if v.String() != "one.bin: LOAD of symbol 'nofunc' limited to 10 bytes, but entry function may return 20 [load-size]" {
+				t.Fatalf("unexpected issue: %s", v)
+			}
+			c++
+		}
+	}
+	if c != 1 {
+		t.Fatalf("expected 1 load-size issue, got %d: %v", c, r)
+	}
+
+	fns = resource.NewFuncRegistry()
+	fns.MustRegister(resource.FuncInfo{
+		Sym: "nofunc",
+		Flags: []uint32{2, 8, 9},
+		Func: func(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+			return resource.Result{}, nil
+		},
+	})
+	rs.WithFuncRegistry(fns)
+	cfg.FlagCount = 1
+	r, err = Lint(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []string{
+		"one.bin: entry function for symbol 'nofunc' may change builtin flag 2, which is not writeable [func-flags]",
+		"one.bin: entry function for symbol 'nofunc' may change flag 9, beyond the 1 flags of the application [func-flags]",
+	}
+	c = 0
+	for _, v := range r {
+		if v.Check != CHECK_FUNC_FLAGS {
+			continue
+		}
+		if c >= len(expect) || v.String() != expect[c] {
+			t.Fatalf("unexpected issue: %s", v)
+		}
+		c++
+	}
+	if c != len(expect) {
+		t.Fatalf("expected %d func-flags issues, got %d: %v", len(expect), c, r)
+	}
+}
+
+func TestLintInclude(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := map[string]string{
+		"root.vis": `INCLUDE "menu.vis"
+LOAD foo 0
+MAP baz
+DOWN one 1 go_one
+UP 0 back
+`,
+		"menu.vis": `FLAG suspended 9
+MACRO header
+MOUT help 9
+ENDMACRO
+CATCH _catch suspended 1
+header
+`,
+		"one.vis": `HALT
+`,
+	}
+	for k, v := range src {
+		err := os.WriteFile(path.Join(dir, k), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, k := range []string{"root", "one"} {
+		b := bytes.NewBuffer(nil)
+		_, err := asm.ParseFile(path.Join(dir, k + ".vis"), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path.Join(dir, k + ".bin"), b.Bytes(), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for k, v := range testFiles {
+		err := os.WriteFile(path.Join(dir, k), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rs := resource.NewFsResource(dir)
+	cfg := Config{
+		SourceDir: dir,
+		Skip: []string{CHECK_MISSING_TEMPLATE, CHECK_MISSING_NODE},
+	}
+	r, err := Lint(ctx, cfg, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"root.vis:3: MAP of symbol 'baz' which is never loaded [map-unloaded]",
+		"menu.vis:3: no INCMP for menu selector '9' [unmatched-menu]",
+	}
+	if len(r) != len(expect) {
+		t.Fatalf("expected %d issues, got %d: %v", len(expect), len(r), r)
+	}
+	for i, v := range r {
+		s := v.String()[len(dir)+1:]
+		if s != expect[i] {
+			t.Fatalf("issue %d: expected\n%s\ngot\n%s", i, expect[i], s)
+		}
+	}
+}
+
+func TestLintMemResource(t *testing.T) {
+	ctx := context.Background()
+	rs := resource.NewMemResource()
+	for k, v := range testSources {
+		b := bytes.NewBuffer(nil)
+		_, err := asm.Parse(v, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.AddBytecode(k, b.Bytes())
+		rs.AddTemplate(k, k)
+	}
+	cfg := Config{
+		Skip: []string{CHECK_DUPLICATE_SELECTOR},
+	}
+	r, err := Lint(ctx, cfg, &rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) == 0 || r[0].String() != "root.bin: INCMP target 'nope' has no bytecode [missing-node]" {
+		t.Fatalf("expected missing node issue, got %v", r)
+	}
+}
@@ -0,0 +1,13 @@
+package lint
+
+import (
+	"git.defalsify.org/vise.git/logging"
+)
+
+var (
+	Logg logging.Logger = logging.NewVanilla().WithDomain("lint")
+)
+
+func init() {
+	logging.Register("lint", &Logg)
+}
@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// JSON is a structured logger emitting one JSON object per log line.
+//
+// Each line includes the level, domain and caller information. If the logging context carries a "SessionId" value, it is included aswell. The structured log args are added as separate fields.
+type JSON struct {
+	domain string
+	levelFilter int
+}
+
+// NewJSON creates a new JSON logger.
+func NewJSON() JSON {
+	return JSON{
+		domain: "main",
+		levelFilter: LogLevel,
+	}
+}
+
+// WithDomain sets the logging domain.
+func(j JSON) WithDomain(domain string) JSON {
+	j.domain = domain
+	return j
+}
+
+// WithLevel overrides the globally set loglevel for the logger instance.
+func(j JSON) WithLevel(level int) JSON {
+	j.levelFilter = level
+	return j
+}
+
+// compile log line from inputs and send to given writer.
+func(j JSON) writef(ctx context.Context, w io.Writer, file string, line int, level int, msg string, args ...any) {
+	if level > j.levelFilter {
+		return
+	}
+	o := argsToFields(args)
+	o["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	o["level"] = levelName[level]
+	o["domain"] = j.domain
+	o["caller"] = fmt.Sprintf("%s:%v", file, line)
+	if len(msg) > 0 {
+		o["msg"] = msg
+	}
+	if ctx != nil {
+		v := ctx.Value("SessionId")
+		if v != nil {
+			o["session"] = v
+		}
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		b, _ = json.Marshal(map[string]any{
+			"level": levelName[LVL_ERROR],
+			"domain": j.domain,
+			"msg": fmt.Sprintf("log encode failed: %v", err),
+		})
+	}
+	b = append(b, 0x0a)
+	w.Write(b)
+}
+
+// Printf logs to the global writer.
+func(j JSON) Printf(level int, msg string, args ...any) {
+	file, line := getCaller(2)
+	j.writef(nil, LogWriter, file, line, level, msg, args...)
+}
+
+// PrintCtxf logs with context to the global writer.
+func(j JSON) PrintCtxf(ctx context.Context, level int, msg string, args ...any) {
+	file, line := getCaller(2)
+	j.writef(ctx, LogWriter, file, line, level, msg, args...)
+}
+
+// Writef logs to the given writer.
+func(j JSON) Writef(w io.Writer, level int, msg string, args ...any) {
+	file, line := getCaller(2)
+	j.writef(nil, w, file, line, level, msg, args...)
+}
+
+// WriteCtxf logs with context to the given writer.
+func(j JSON) WriteCtxf(ctx context.Context, w io.Writer, level int, msg string, args ...any) {
+	file, line := getCaller(2)
+	j.writef(ctx, w, file, line, level, msg, args...)
+}
+
+// get caller information and pass on to writef
+func(j JSON) printf(level int, msg string, args ...any) {
+	file, line := getCaller(3)
+	j.writef(nil, LogWriter, file, line, level, msg, args...)
+}
+
+// get caller information and pass on to writef
+func(j JSON) printCtxf(ctx context.Context, level int, msg string, args ...any) {
+	file, line := getCaller(3)
+	j.writef(ctx, LogWriter, file, line, level, msg, args...)
+}
+
+// Tracef logs a line with level TRACE to the global writer.
+func(j JSON) Tracef(msg string, args ...any) {
+	j.printf(LVL_TRACE, msg, args...)
+}
+
+// Debugf logs a line with level DEBUG to the global writer.
+func(j JSON) Debugf(msg string, args ...any) {
+	j.printf(LVL_DEBUG, msg, args...)
+}
+
+// Infof logs a line with level INFO to the global writer.
+func(j JSON) Infof(msg string, args ...any) {
+	j.printf(LVL_INFO, msg, args...)
+}
+
+// Warnf logs a line with level WARN to the global writer.
+func(j JSON) Warnf(msg string, args ...any) {
+	j.printf(LVL_WARN, msg, args...)
+}
+
+// Errorf logs a line with level ERROR to the global writer.
+func(j JSON) Errorf(msg string, args ...any) {
+	j.printf(LVL_ERROR, msg, args...)
+}
+
+// TraceCtxf logs a line with context with level TRACE to the global writer.
+func(j JSON) TraceCtxf(ctx context.Context, msg string, args ...any) {
+	j.printCtxf(ctx, LVL_TRACE, msg, args...)
+}
+
+// DebugCtxf logs a line with context with level DEBUG to the global writer.
+func(j JSON) DebugCtxf(ctx context.Context, msg string, args ...any) {
+	j.printCtxf(ctx, LVL_DEBUG, msg, args...)
+}
+
+// InfoCtxf logs a line with context with level INFO to the global writer.
+func(j JSON) InfoCtxf(ctx context.Context, msg string, args ...any) {
+	j.printCtxf(ctx, LVL_INFO, msg, args...)
+}
+
+// WarnCtxf logs a line with context with level WARN to the global writer.
+func(j JSON) WarnCtxf(ctx context.Context, msg string, args ...any) {
+	j.printCtxf(ctx, LVL_WARN, msg, args...)
+}
+
+// ErrorCtxf logs a line with context with level ERROR to the global writer.
+func(j JSON) ErrorCtxf(ctx context.Context, msg string, args ...any) {
+	j.printCtxf(ctx, LVL_ERROR, msg, args...)
+}
+
+// map structured log args to json encodable fields.
+//
+// keys colliding with the builtin fields are prefixed with an underscore.
+func argsToFields(args []any) map[string]any {
+	o := make(map[string]any)
+	c := len(args)
+	for i := 0; i < c; i += 2 {
+		k := fmt.Sprintf("%v", args[i])
+		switch k {
+		case "time", "level", "domain", "caller", "msg", "session":
+			k = "_" + k
+		}
+		if i + 1 >= c {
+			o[k] = nil
+			continue
+		}
+		o[k] = fieldValue(args[i+1])
+	}
+	return o
+}
+
+// json encodable representation of a single structured log value.
+func fieldValue(v any) any {
+	switch vv := v.(type) {
+	case []byte:
+		return fmt.Sprintf("%x", vv)
+	case error:
+		return vv.Error()
+	case fmt.Stringer:
+		return vv.String()
+	}
+	_, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	logg := NewJSON().WithDomain("test").WithLevel(LVL_WARN)
+	w := bytes.NewBuffer(nil)
+	logg.Writef(w, LVL_DEBUG, "message", "xyzzy", 666, "inky", "pinky")
+	if len(w.Bytes()) > 0 {
+		t.Errorf("expected nothing, got %s", w.Bytes())
+	}
+	logg = logg.WithLevel(LVL_DEBUG)
+	logg.Writef(w, LVL_DEBUG, "message", "xyzzy", 666, "inky", "pinky", "code", []byte{0x2a})
+	var o map[string]any
+	err := json.Unmarshal(w.Bytes(), &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o["level"] != "DEBUG" {
+		t.Fatalf("expected level 'DEBUG', got %v", o["level"])
+	}
+	if o["domain"] != "test" {
+		t.Fatalf("expected domain 'test', got %v", o["domain"])
+	}
+	if o["msg"] != "message" {
+		t.Fatalf("expected msg 'message', got %v", o["msg"])
+	}
+	if o["xyzzy"] != float64(666) {
+		t.Fatalf("expected xyzzy 666, got %v", o["xyzzy"])
+	}
+	if o["inky"] != "pinky" {
+		t.Fatalf("expected inky 'pinky', got %v", o["inky"])
+	}
+	if o["code"] != "2a" {
+		t.Fatalf("expected code '2a', got %v", o["code"])
+	}
+	caller, ok := o["caller"].(string)
+	if !ok || !strings.HasPrefix(caller, "json_test.go:") {
+		t.Fatalf("expected caller in json_test.go, got %v", o["caller"])
+	}
+	_, ok = o["session"]
+	if ok {
+		t.Fatalf("expected no session field")
+	}
+}
+
+func TestJSONContext(t *testing.T) {
+	logg := NewJSON().WithDomain("test").WithLevel(LVL_DEBUG)
+	ctx := context.WithValue(context.TODO(), "SessionId", "+254712345678")
+	w := bytes.NewBuffer(nil)
+	logg.WriteCtxf(ctx, w, LVL_INFO, "", "level", "shadowed")
+	var o map[string]any
+	err := json.Unmarshal(w.Bytes(), &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o["session"] != "+254712345678" {
+		t.Fatalf("expected session from context, got %v", o["session"])
+	}
+	if o["level"] != "INFO" {
+		t.Fatalf("expected level 'INFO', got %v", o["level"])
+	}
+	if o["_level"] != "shadowed" {
+		t.Fatalf("expected prefixed arg for builtin field, got %v", o["_level"])
+	}
+	_, ok := o["msg"]
+	if ok {
+		t.Fatalf("expected no msg field for empty message")
+	}
+}
+
+func TestUse(t *testing.T) {
+	var logg Logger = NewVanilla().WithDomain("foo")
+	Register("foo", &logg)
+	defer Use(VanillaFunc)
+	Use(JSONFunc)
+	_, ok := logg.(JSON)
+	if !ok {
+		t.Fatalf("expected JSON logger, got %T", logg)
+	}
+	var loggLate Logger = NewVanilla().WithDomain("bar")
+	Register("bar", &loggLate)
+	r, ok := loggLate.(JSON)
+	if !ok {
+		t.Fatalf("expected JSON logger for late registration, got %T", loggLate)
+	}
+	if r.domain != "bar" {
+		t.Fatalf("expected domain 'bar', got '%s'", r.domain)
+	}
+}
@@ -23,12 +23,24 @@ var (
 		LVL_DEBUG: "D",	
 		LVL_TRACE: "T",	
 	}
+	levelName = map[int]string{
+		LVL_ERROR: "ERROR",
+		LVL_WARN: "WARN",
+		LVL_INFO: "INFO",
+		LVL_DEBUG: "DEBUG",
+		LVL_TRACE: "TRACE",
+	}
 )
 
 var (
 	LogWriter = os.Stderr
+	loggers = make(map[string][]*Logger)
+	loggerFunc LoggerFunc
 )
 
+// LoggerFunc creates a new Logger for the given logging domain.
+type LoggerFunc func(domain string) Logger
+
 
 func AsString(level int) string {
 	return levelStr[level]	
@@ -52,3 +64,34 @@ type Logger interface {
 	ErrorCtxf(ctx context.Context, msg string, args ...any)
 }
 
+// Register makes a package-level logger replaceable by Use.
+//
+// If Use has already been called, the logger is replaced immediately.
+func Register(domain string, logg *Logger) {
+	loggers[domain] = append(loggers[domain], logg)
+	if loggerFunc != nil {
+		*logg = loggerFunc(domain)
+	}
+}
+
+// Use replaces all registered package-level loggers with loggers created by the given function.
+//
+// It should be called once at startup, before any execution takes place.
+func Use(fn LoggerFunc) {
+	loggerFunc = fn
+	for domain, v := range loggers {
+		for _, logg := range v {
+			*logg = fn(domain)
+		}
+	}
+}
+
+// VanillaFunc is a LoggerFunc creating Vanilla loggers.
+func VanillaFunc(domain string) Logger {
+	return NewVanilla().WithDomain(domain)
+}
+
+// JSONFunc is a LoggerFunc creating JSON loggers.
+func JSONFunc(domain string) Logger {
+	return NewJSON().WithDomain(domain)
+}
@@ -0,0 +1,271 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+const (
+	// SlogLevelTrace is the slog level corresponding to LVL_TRACE.
+	SlogLevelTrace = slog.LevelDebug - 4
+)
+
+// SlogLevel returns the slog level corresponding to the given vise loglevel.
+func SlogLevel(level int) slog.Level {
+	switch level {
+	case LVL_ERROR:
+		return slog.LevelError
+	case LVL_WARN:
+		return slog.LevelWarn
+	case LVL_INFO:
+		return slog.LevelInfo
+	case LVL_DEBUG:
+		return slog.LevelDebug
+	}
+	return SlogLevelTrace
+}
+
+// LevelFromSlog returns the vise loglevel corresponding to the given slog level.
+func LevelFromSlog(level slog.Level) int {
+	if level >= slog.LevelError {
+		return LVL_ERROR
+	} else if level >= slog.LevelWarn {
+		return LVL_WARN
+	} else if level >= slog.LevelInfo {
+		return LVL_INFO
+	} else if level >= slog.LevelDebug {
+		return LVL_DEBUG
+	}
+	return LVL_TRACE
+}
+
+// Slog is a Logger that passes log lines on to a slog.Handler.
+//
+// The structured log args are added as slog attributes, and the logging domain is added as the "domain" attribute.
+//
+// The slog.Handler defines the output destination. The io.Writer arguments of Writef and WriteCtxf are ignored.
+type Slog struct {
+	handler slog.Handler
+	domain string
+	levelFilter int
+}
+
+// NewSlog creates a new Slog logger using the given handler.
+func NewSlog(handler slog.Handler) Slog {
+	return Slog{
+		handler: handler,
+		domain: "main",
+		levelFilter: LogLevel,
+	}
+}
+
+// WithDomain sets the logging domain.
+func(s Slog) WithDomain(domain string) Slog {
+	s.domain = domain
+	return s
+}
+
+// WithLevel overrides the globally set loglevel for the logger instance.
+func(s Slog) WithLevel(level int) Slog {
+	s.levelFilter = level
+	return s
+}
+
+// create slog record from inputs and pass to handler.
+//
+// depth is the number of stack frames to skip to reach the caller of the public log method.
+func(s Slog) writef(ctx context.Context, depth int, level int, msg string, args ...any) {
+	if level > s.levelFilter {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	lvl := SlogLevel(level)
+	if !s.handler.Enabled(ctx, lvl) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(depth + 1, pcs[:])
+	r := slog.NewRecord(time.Now(), lvl, msg, pcs[0])
+	r.AddAttrs(slog.String("domain", s.domain))
+	r.AddAttrs(argsToAttrs(args)...)
+	err := s.handler.Handle(ctx, r)
+	if err != nil {
+		fmt.Fprintf(LogWriter, "slog handler failed: %v\n", err)
+	}
+}
+
+// Printf implements Logger.
+func(s Slog) Printf(level int, msg string, args ...any) {
+	s.writef(nil, 2, level, msg, args...)
+}
+
+// PrintCtxf implements Logger.
+func(s Slog) PrintCtxf(ctx context.Context, level int, msg string, args ...any) {
+	s.writef(ctx, 2, level, msg, args...)
+}
+
+// Writef implements Logger.
+func(s Slog) Writef(w io.Writer, level int, msg string, args ...any) {
+	s.writef(nil, 2, level, msg, args...)
+}
+
+// WriteCtxf implements Logger.
+func(s Slog) WriteCtxf(ctx context.Context, w io.Writer, level int, msg string, args ...any) {
+	s.writef(ctx, 2, level, msg, args...)
+}
+
+// Tracef implements Logger.
+func(s Slog) Tracef(msg string, args ...any) {
+	s.writef(nil, 2, LVL_TRACE, msg, args...)
+}
+
+// Debugf implements Logger.
+func(s Slog) Debugf(msg string, args ...any) {
+	s.writef(nil, 2, LVL_DEBUG, msg, args...)
+}
+
+// Infof implements Logger.
+func(s Slog) Infof(msg string, args ...any) {
+	s.writef(nil, 2, LVL_INFO, msg, args...)
+}
+
+// Warnf implements Logger.
+func(s Slog) Warnf(msg string, args ...any) {
+	s.writef(nil, 2, LVL_WARN, msg, args...)
+}
+
+// Errorf implements Logger.
+func(s Slog) Errorf(msg string, args ...any) {
+	s.writef(nil, 2, LVL_ERROR, msg, args...)
+}
+
+// TraceCtxf implements Logger.
+func(s Slog) TraceCtxf(ctx context.Context, msg string, args ...any) {
+	s.writef(ctx, 2, LVL_TRACE, msg, args...)
+}
+
+// DebugCtxf implements Logger.
+func(s Slog) DebugCtxf(ctx context.Context, msg string, args ...any) {
+	s.writef(ctx, 2, LVL_DEBUG, msg, args...)
+}
+
+// InfoCtxf implements Logger.
+func(s Slog) InfoCtxf(ctx context.Context, msg string, args ...any) {
+	s.writef(ctx, 2, LVL_INFO, msg, args...)
+}
+
+// WarnCtxf implements Logger.
+func(s Slog) WarnCtxf(ctx context.Context, msg string, args ...any) {
+	s.writef(ctx, 2, LVL_WARN, msg, args...)
+}
+
+// ErrorCtxf implements Logger.
+func(s Slog) ErrorCtxf(ctx context.Context, msg string, args ...any) {
+	s.writef(ctx, 2, LVL_ERROR, msg, args...)
+}
+
+// SlogHandler is a slog.Handler that passes log records on to a Logger.
+//
+// Record attributes are passed as structured log args. Attributes in groups get the group names prepended to the key, separated by ".".
+type SlogHandler struct {
+	logg Logger
+	attrs []any
+	group string
+}
+
+// NewSlogHandler creates a new SlogHandler using the given logger.
+func NewSlogHandler(logg Logger) *SlogHandler {
+	return &SlogHandler{
+		logg: logg,
+	}
+}
+
+// Enabled implements slog.Handler.
+//
+// Level filtering is left to the Logger, so it always returns true.
+func(h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func(h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	args := append([]any{}, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		args = appendAttr(args, h.group, a)
+		return true
+	})
+	h.logg.PrintCtxf(ctx, LevelFromSlog(r.Level), r.Message, args...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func(h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hh := *h
+	hh.attrs = append([]any{}, h.attrs...)
+	for _, a := range attrs {
+		hh.attrs = appendAttr(hh.attrs, h.group, a)
+	}
+	return &hh
+}
+
+// WithGroup implements slog.Handler.
+func(h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	hh := *h
+	hh.group = groupKey(h.group, name)
+	return &hh
+}
+
+// prefix key with group name.
+func groupKey(group string, key string) string {
+	if group == "" {
+		return key
+	}
+	return group + "." + key
+}
+
+// flatten slog attribute to key/value pair structured log args.
+func appendAttr(args []any, group string, a slog.Attr) []any {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group = groupKey(group, a.Key)
+		}
+		for _, aa := range v.Group() {
+			args = appendAttr(args, group, aa)
+		}
+		return args
+	}
+	if a.Key == "" {
+		return args
+	}
+	return append(args, groupKey(group, a.Key), v.Any())
+}
+
+// convert structured log args to slog attributes.
+func argsToAttrs(args []any) []slog.Attr {
+	var r []slog.Attr
+	c := len(args)
+	for i := 0; i < c; i += 2 {
+		k := fmt.Sprintf("%v", args[i])
+		if i + 1 >= c {
+			r = append(r, slog.Any(k, nil))
+			continue
+		}
+		v, ok := args[i+1].([]byte)
+		if ok {
+			r = append(r, slog.String(k, fmt.Sprintf("%x", v)))
+		} else {
+			r = append(r, slog.Any(k, args[i+1]))
+		}
+	}
+	return r
+}
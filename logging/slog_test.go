@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+type testLogger struct {
+	Vanilla
+	level int
+	msg string
+	args []any
+	ctx context.Context
+}
+
+func(l *testLogger) PrintCtxf(ctx context.Context, level int, msg string, args ...any) {
+	l.ctx = ctx
+	l.level = level
+	l.msg = msg
+	l.args = args
+}
+
+func TestSlog(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level: SlogLevelTrace,
+	})
+	logg := NewSlog(h).WithDomain("test").WithLevel(LVL_WARN)
+	logg.Debugf("message", "xyzzy", 666)
+	if len(w.Bytes()) > 0 {
+		t.Fatalf("expected nothing, got %s", w.Bytes())
+	}
+	logg = logg.WithLevel(LVL_TRACE)
+	logg.Tracef("message", "xyzzy", 666, "inky", "pinky", "code", []byte{0x2a})
+
+	var o map[string]any
+	err := json.Unmarshal(w.Bytes(), &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o["level"] != "DEBUG-4" {
+		t.Fatalf("expected level 'DEBUG-4', got %v", o["level"])
+	}
+	if o["msg"] != "message" {
+		t.Fatalf("expected msg 'message', got %v", o["msg"])
+	}
+	if o["domain"] != "test" {
+		t.Fatalf("expected domain 'test', got %v", o["domain"])
+	}
+	if o["xyzzy"] != float64(666) {
+		t.Fatalf("expected xyzzy 666, got %v", o["xyzzy"])
+	}
+	if o["inky"] != "pinky" {
+		t.Fatalf("expected inky 'pinky', got %v", o["inky"])
+	}
+	if o["code"] != "2a" {
+		t.Fatalf("expected code '2a', got %v", o["code"])
+	}
+	src, ok := o["source"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected source, got %v", o["source"])
+	}
+	if src["function"] != "git.defalsify.org/vise.git/logging.TestSlog" {
+		t.Fatalf("expected source function of caller, got %v", src["function"])
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	logg := &testLogger{}
+	ctx := context.WithValue(context.TODO(), "SessionId", "xyzzy")
+	sl := slog.New(NewSlogHandler(logg)).With("inky", "pinky").WithGroup("foo")
+	sl.WarnContext(ctx, "message", "bar", 42, slog.Group("baz", "blinky", "clyde"))
+	if logg.level != LVL_WARN {
+		t.Fatalf("expected level %v, got %v", LVL_WARN, logg.level)
+	}
+	if logg.msg != "message" {
+		t.Fatalf("expected msg 'message', got '%s'", logg.msg)
+	}
+	if logg.ctx.Value("SessionId") != "xyzzy" {
+		t.Fatalf("expected context to be passed on")
+	}
+	expect := []any{"inky", "pinky", "foo.bar", int64(42), "foo.baz.blinky", "clyde"}
+	if len(logg.args) != len(expect) {
+		t.Fatalf("expected args %v, got %v", expect, logg.args)
+	}
+	for i, v := range expect {
+		if logg.args[i] != v {
+			t.Fatalf("expected args %v, got %v", expect, logg.args)
+		}
+	}
+
+	sl.Debug("trace", "xyzzy", 13)
+	if logg.level != LVL_DEBUG {
+		t.Fatalf("expected level %v, got %v", LVL_DEBUG, logg.level)
+	}
+}
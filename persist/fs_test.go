@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"reflect"
 	"testing"
+	"time"
 
 	"git.defalsify.org/vise.git/cache"
 	"git.defalsify.org/vise.git/state"
@@ -26,6 +27,7 @@ func TestSerializeState(t *testing.T) {
 	ca := cache.NewCache().WithCacheSize(1024)
 	ca.Add("inky", "pinky", 13)
 	ca.Add("blinky", "clyde", 42)
+	ca.SetMaxAge("inky", time.Minute)
 
 	pr := NewFsPersister(".").WithContent(&st, ca)
 	v, err := pr.Serialize()
@@ -53,6 +55,14 @@ func TestSerializeState(t *testing.T) {
 	if !reflect.DeepEqual(prnew.Memory, pr.Memory) {
 		t.Fatalf("expected %v, got %v", prnew.Memory, pr.Memory)
 	}
+	maxAge, ok := prnew.Memory.MaxAge("inky")
+	if !ok || maxAge != time.Minute {
+		t.Fatalf("expected max age %v, got %v", time.Minute, maxAge)
+	}
+	_, err = prnew.Memory.Age("blinky")
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestSaveLoad(t *testing.T) {
@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("persist")
 )
+
+func init() {
+	logging.Register("persist", &Logg)
+}
@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("render")
 )
+
+func init() {
+	logging.Register("render", &Logg)
+}
@@ -70,6 +70,13 @@ func(m *Menu) WithPageCount(pageCount uint16) *Menu {
 	return m
 }
 
+// PageCount returns the number of pages the menu currently represents.
+//
+// The page count is updated by Page.Render when the page has paged content.
+func(m *Menu) PageCount() uint16 {
+	return m.pageCount
+}
+
 func(m *Menu) WithPages() *Menu {
 	if m.pageCount == 0 {
 		m.pageCount = 1
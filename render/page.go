@@ -147,20 +147,7 @@ func(pg *Page) Sizes() (map[string]uint16, error) {
 
 // RenderTemplate is an adapter to implement the builtin golang text template renderer as resource.RenderTemplate.
 func(pg *Page) RenderTemplate(ctx context.Context, sym string, values map[string]string, idx uint16) (string, error) {
-	tpl, err := pg.resource.GetTemplate(ctx, sym)
-	if err != nil {
-		return "", err
-	}
-	tpl += pg.extra
-	if pg.err != nil {
-		derr := pg.Error()
-		Logg.DebugCtxf(ctx, "prepending error", "err", pg.err, "display", derr)
-		if len(tpl) == 0 {
-			tpl = derr
-		} else {
-			tpl = fmt.Sprintf("%s\n%s", derr, tpl)
-		}
-	}
+	var err error
 	if pg.sizer != nil {
 		values, err = pg.sizer.GetAt(values, idx)
 		if err != nil {
@@ -170,8 +157,8 @@ func(pg *Page) RenderTemplate(ctx context.Context, sym string, values map[string
 		return "", fmt.Errorf("sizer needed for indexed render")
 	}
 	Logg.Debugf("render for", "index", idx)
-	
-	tp, err := template.New("tester").Option("missingkey=error").Parse(tpl)
+
+	tp, err := pg.getTemplate(ctx, sym)
 	if err != nil {
 		return "", err
 	}
@@ -184,6 +171,33 @@ func(pg *Page) RenderTemplate(ctx context.Context, sym string, values map[string
 	return b.String(), err
 }
 
+// retrieve the parsed template for the symbol, with extra content and error state applied.
+//
+// the pre-parsed template of the resource is used if available and the template is not modified.
+func(pg *Page) getTemplate(ctx context.Context, sym string) (*template.Template, error) {
+	if pg.extra == "" && pg.err == nil {
+		tpr, ok := pg.resource.(resource.TemplateParser)
+		if ok {
+			return tpr.GetParsedTemplate(ctx, sym)
+		}
+	}
+	tpl, err := pg.resource.GetTemplate(ctx, sym)
+	if err != nil {
+		return nil, err
+	}
+	tpl += pg.extra
+	if pg.err != nil {
+		derr := pg.Error()
+		Logg.DebugCtxf(ctx, "prepending error", "err", pg.err, "display", derr)
+		if len(tpl) == 0 {
+			tpl = derr
+		} else {
+			tpl = fmt.Sprintf("%s\n%s", derr, tpl)
+		}
+	}
+	return resource.ParseTemplate(tpl)
+}
+
 // Render renders the current mapped content and menu state against the template associated with the symbol.
 func(pg *Page) Render(ctx context.Context, sym string, idx uint16) (string, error) {
 	var err error
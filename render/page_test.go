@@ -145,3 +145,32 @@ bar
 	}
 }
 
+
+func TestRenderCachedTemplate(t *testing.T) {
+	ca := cache.NewCache()
+	mr := resource.NewMemResource()
+	mr.AddTemplate("foo", "bar {{.baz}}")
+	rs := resource.NewCachedResource(mr)
+	pg := NewPage(ca, rs)
+
+	ctx := context.TODO()
+	values := map[string]string{"baz": "xyzzy"}
+	r, err := pg.RenderTemplate(ctx, "foo", values, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "bar xyzzy" {
+		t.Fatalf("unexpected render: %s", r)
+	}
+
+	pg = pg.WithError(fmt.Errorf("my humps"))
+	r, err = pg.RenderTemplate(ctx, "foo", values, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `my humps
+bar xyzzy`
+	if r != expect {
+		t.Fatalf("expected:\n\t%s\ngot:\n\t%s", expect, r)
+	}
+}
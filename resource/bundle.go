@@ -0,0 +1,246 @@
+package resource
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/lang"
+)
+
+const (
+	// Name of the manifest file in a bundle.
+	BUNDLE_MANIFEST = "manifest.json"
+	// Version of the bundle format.
+	BUNDLE_VERSION = 1
+)
+
+// BundleFile describes a single file in a bundle.
+type BundleFile struct {
+	Name string `json:"name"`
+	Size int64 `json:"size"`
+	Hash string `json:"sha256"`
+}
+
+// BundleManifest lists the contents of a bundle.
+type BundleManifest struct {
+	Version int `json:"version"`
+	VmVersion int `json:"vm_version"`
+	Files []BundleFile `json:"files"`
+}
+
+// BundleResource serves bytecode, templates, menus and static entry function contents from a single zip archive.
+//
+// The archive has the same layout as a resource directory for FsResource, and is created with WriteBundle. Lookups are done by an FsysResource over the archive.
+type BundleResource struct {
+	*FsysResource
+	Manifest BundleManifest
+	closer io.Closer
+}
+
+// NewBundleResource opens the bundle at the given path.
+//
+// The contents of the bundle are verified against the manifest. The bundle should be closed with Close when no longer used.
+func NewBundleResource(fp string) (*BundleResource, error) {
+	zr, err := zip.OpenReader(fp)
+	if err != nil {
+		return nil, err
+	}
+	br, err := newBundleResource(&zr.Reader)
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	br.closer = zr
+	return br, nil
+}
+
+// NewBundleResourceFromReader reads a bundle of the given size from the reader.
+//
+// The contents of the bundle are verified against the manifest.
+func NewBundleResourceFromReader(r io.ReaderAt, size int64) (*BundleResource, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newBundleResource(zr)
+}
+
+func newBundleResource(zr *zip.Reader) (*BundleResource, error) {
+	br := &BundleResource{
+		FsysResource: NewFsysResource(zr),
+	}
+	b, err := fs.ReadFile(zr, BUNDLE_MANIFEST)
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no manifest: %v", err)
+	}
+	err = json.Unmarshal(b, &br.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("bundle manifest invalid: %v", err)
+	}
+	if br.Manifest.Version != BUNDLE_VERSION {
+		return nil, fmt.Errorf("bundle version %d not supported, expected %d", br.Manifest.Version, BUNDLE_VERSION)
+	}
+	if br.Manifest.VmVersion != bytecode.VM_VERSION {
+		return nil, fmt.Errorf("bundle cannot be opened: %w", bytecode.VersionError{Version: uint16(br.Manifest.VmVersion)})
+	}
+	for _, v := range br.Manifest.Files {
+		b, err := fs.ReadFile(zr, v.Name)
+		if err != nil {
+			return nil, fmt.Errorf("bundle file '%s' missing: %v", v.Name, err)
+		}
+		h := sha256.Sum256(b)
+		if hex.EncodeToString(h[:]) != v.Hash {
+			return nil, fmt.Errorf("bundle file '%s' does not match manifest", v.Name)
+		}
+	}
+	Logg.Debugf("opened bundle", "files", len(br.Manifest.Files))
+	return br, nil
+}
+
+// Close closes the bundle file, if opened with NewBundleResource.
+func(br *BundleResource) Close() error {
+	if br.closer == nil {
+		return nil
+	}
+	return br.closer.Close()
+}
+
+// String implements the String interface.
+func(br *BundleResource) String() string {
+	return fmt.Sprintf("bundle resource with %d files", len(br.Manifest.Files))
+}
+
+// bundled returns true if the file in a resource directory belongs in a bundle.
+//
+// These are bytecode (<sym>.bin), static entry function contents (<sym>.txt), menus (<sym>_menu) and the templates of the nodes with bytecode (<sym>), each with their language variants (<sym>_<lang>). Hidden files are excluded.
+func bundled(name string, nodes map[string]bool) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	switch path.Ext(name) {
+	case ".bin", ".txt":
+		return true
+	case "":
+	default:
+		return false
+	}
+	if nodes[name] || strings.HasSuffix(name, "_menu") {
+		return true
+	}
+	i := strings.LastIndex(name, "_")
+	if i == -1 {
+		return false
+	}
+	_, err := lang.LanguageFromCode(name[i+1:])
+	if err != nil {
+		return false
+	}
+	name = name[:i]
+	return nodes[name] || strings.HasSuffix(name, "_menu")
+}
+
+// the regular files among the directory entries that belong in a bundle.
+func bundledEntries(entries []os.DirEntry) []os.DirEntry {
+	var r []os.DirEntry
+	nodes := make(map[string]bool)
+	for _, v := range entries {
+		if v.Type().IsRegular() && path.Ext(v.Name()) == ".bin" {
+			nodes[strings.TrimSuffix(v.Name(), ".bin")] = true
+		}
+	}
+	for _, v := range entries {
+		if v.Type().IsRegular() && bundled(v.Name(), nodes) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// WriteBundle writes a bundle of the resource directory to the writer.
+//
+// Only files serving as bytecode, templates, menus or static entry function contents are included. Files without extension are only taken as templates if bytecode exists for them. Files matching any of the exclude patterns, as defined by path.Match, are not included either. Subdirectories are ignored.
+func WriteBundle(w io.Writer, dir string, exclude []string) (*BundleManifest, error) {
+	mf := &BundleManifest{
+		Version: BUNDLE_VERSION,
+		VmVersion: bytecode.VM_VERSION,
+		Files: []BundleFile{},
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, v := range bundledEntries(entries) {
+		if v.Name() == BUNDLE_MANIFEST {
+			continue
+		}
+		skip := false
+		for _, x := range exclude {
+			ok, err := path.Match(x, v.Name())
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			names = append(names, v.Name())
+		}
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, v := range names {
+		b, err := os.ReadFile(path.Join(dir, v))
+		if err != nil {
+			return nil, err
+		}
+		err = writeBundleFile(zw, v, b)
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.Sum256(b)
+		mf.Files = append(mf.Files, BundleFile{
+			Name: v,
+			Size: int64(len(b)),
+			Hash: hex.EncodeToString(h[:]),
+		})
+	}
+	b, err := json.MarshalIndent(mf, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	err = writeBundleFile(zw, BUNDLE_MANIFEST, b)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return mf, nil
+}
+
+func writeBundleFile(zw *zip.Writer, name string, b []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name: name,
+		Method: zip.Deflate,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	return err
+}
@@ -0,0 +1,189 @@
+package resource
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/lang"
+)
+
+func writeBundleTestDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"root": "hello {{.foo}}",
+		"root_nor": "hei {{.foo}}",
+		"root.vis": "HALT\n",
+		"root.bin": string(bytecode.Encode(bytecode.Header{VmVersion: bytecode.VM_VERSION}, []byte{0x00, 0x07})),
+		"next_menu": "go on",
+		"foo.txt": "world",
+		"foo_nor.txt": "verden",
+		"Makefile": "all:",
+		"README": "about",
+		"back_menu_nor": "tilbake",
+		"root_xyzzy": "not a language",
+		".vise.sum": "",
+	}
+	for k, v := range files {
+		err := os.WriteFile(path.Join(dir, k), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBundle(t *testing.T) {
+	dir := writeBundleTestDir(t)
+	b := bytes.NewBuffer(nil)
+	mf, err := WriteBundle(b, dir, []string{"foo_*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range mf.Files {
+		names = append(names, v.Name)
+	}
+	expect := "back_menu_nor,foo.txt,next_menu,root,root.bin,root_nor"
+	if strings.Join(names, ",") != expect {
+		t.Fatalf("expected files %s, got %s", expect, strings.Join(names, ","))
+	}
+
+	rs, err := NewBundleResourceFromReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s, err := rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "hello {{.foo}}" {
+		t.Fatalf("unexpected template: %s", s)
+	}
+	code, err := rs.GetCode("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, []byte{0x00, 0x07}) {
+		t.Fatalf("unexpected code: %x", code)
+	}
+	s, err = rs.GetMenu(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "go on" {
+		t.Fatalf("unexpected menu: %s", s)
+	}
+	s, err = rs.GetMenu(ctx, "back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "back" {
+		t.Fatalf("unexpected menu: %s", s)
+	}
+
+	l, err := lang.LanguageFromCode("nor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = context.WithValue(ctx, "Language", l)
+	s, err = rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "hei {{.foo}}" {
+		t.Fatalf("unexpected template: %s", s)
+	}
+	fn, err := rs.FuncFor("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := fn(ctx, "foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "world" {
+		t.Fatalf("unexpected content: %s", r.Content)
+	}
+	_, err = rs.FuncFor("bar")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	rs.AddLocalFunc("bar", getTestFunc)
+	_, err = rs.FuncFor("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundleFile(t *testing.T) {
+	dir := writeBundleTestDir(t)
+	fp := path.Join(t.TempDir(), "app.zip")
+	f, err := os.Create(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = WriteBundle(f, dir, nil)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := NewBundleResource(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	_, err = rs.GetTemplate(context.Background(), "Makefile")
+	if err == nil {
+		t.Fatalf("expected Makefile not to be bundled without exclude list")
+	}
+	_, err = rs.GetTemplate(context.Background(), "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundleInvalid(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(b)
+	err := writeBundleFile(zw, BUNDLE_MANIFEST, []byte(fmt.Sprintf(`{"version": 1, "vm_version": %d, "files": [{"name": "root", "size": 3, "sha256": "00"}]}`, bytecode.VM_VERSION)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeBundleFile(zw, "root", []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	_, err = NewBundleResourceFromReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestBundleVmVersion(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(b)
+	err := writeBundleFile(zw, BUNDLE_MANIFEST, []byte(fmt.Sprintf(`{"version": 1, "vm_version": %d, "files": []}`, bytecode.VM_VERSION + 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	_, err = NewBundleResourceFromReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	var verr bytecode.VersionError
+	if !errors.As(err, &verr) || verr.Version != bytecode.VM_VERSION + 1 {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func getTestFunc(ctx context.Context, sym string, input []byte) (Result, error) {
+	return Result{}, nil
+}
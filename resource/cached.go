@@ -0,0 +1,196 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+
+	"git.defalsify.org/vise.git/lang"
+)
+
+// TemplateParser is implemented by resources that provide templates already parsed for rendering.
+//
+// The templates are parsed as text/template with the "missingkey=error" option.
+type TemplateParser interface {
+	GetParsedTemplate(ctx context.Context, sym string) (*template.Template, error)
+}
+
+// ParseTemplate parses a template the way it is rendered by render.Page.
+func ParseTemplate(tpl string) (*template.Template, error) {
+	return template.New("tester").Option("missingkey=error").Parse(tpl)
+}
+
+// lookup key of a template or menu in a language and layer selection.
+type cacheKey struct {
+	lang string
+	layers string
+	sym string
+}
+
+// CachedResource caches the bytecode, templates and menus of a resource.
+//
+// Templates and menus are cached per language and per layer selection in the context, so that a LayeredResource may be wrapped. Templates are also cached in parsed form, which render.Page uses when available. Entry functions are not cached.
+//
+// Changes in the wrapped resource are not seen until the symbols are invalidated.
+type CachedResource struct {
+	Resource
+	mu sync.RWMutex
+	code map[string][]byte
+	templates map[cacheKey]string
+	parsed map[cacheKey]*template.Template
+	menus map[cacheKey]string
+}
+
+// NewCachedResource creates a new CachedResource for the given resource.
+func NewCachedResource(rs Resource) *CachedResource {
+	cr := &CachedResource{
+		Resource: rs,
+	}
+	cr.InvalidateAll()
+	return cr
+}
+
+// the key for the symbol in the language and layer selection of the context.
+func keyFor(ctx context.Context, sym string) cacheKey {
+	k := cacheKey{
+		sym: sym,
+	}
+	v := ctx.Value("Language")
+	if v != nil {
+		k.lang = v.(lang.Language).Code
+	}
+	v = ctx.Value("Layers")
+	if v != nil {
+		k.layers = fmt.Sprintf("%q", v)
+	}
+	return k
+}
+
+// GetCode implements Resource interface.
+func(cr *CachedResource) GetCode(sym string) ([]byte, error) {
+	cr.mu.RLock()
+	b, ok := cr.code[sym]
+	cr.mu.RUnlock()
+	if ok {
+		return b, nil
+	}
+	b, err := cr.Resource.GetCode(sym)
+	if err != nil {
+		return nil, err
+	}
+	// limit capacity so that appending to the returned code never changes the cached copy.
+	b = append([]byte{}, b...)
+	b = b[:len(b):len(b)]
+	cr.mu.Lock()
+	cr.code[sym] = b
+	cr.mu.Unlock()
+	return b, nil
+}
+
+// GetTemplate implements Resource interface.
+func(cr *CachedResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	k := keyFor(ctx, sym)
+	cr.mu.RLock()
+	s, ok := cr.templates[k]
+	cr.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+	s, err := cr.Resource.GetTemplate(ctx, sym)
+	if err != nil {
+		return "", err
+	}
+	cr.mu.Lock()
+	cr.templates[k] = s
+	cr.mu.Unlock()
+	return s, nil
+}
+
+// GetParsedTemplate implements TemplateParser interface.
+func(cr *CachedResource) GetParsedTemplate(ctx context.Context, sym string) (*template.Template, error) {
+	k := keyFor(ctx, sym)
+	cr.mu.RLock()
+	tp, ok := cr.parsed[k]
+	cr.mu.RUnlock()
+	if ok {
+		return tp, nil
+	}
+	s, err := cr.GetTemplate(ctx, sym)
+	if err != nil {
+		return nil, err
+	}
+	tp, err = ParseTemplate(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing template for sym '%s': %v", sym, err)
+	}
+	cr.mu.Lock()
+	cr.parsed[k] = tp
+	cr.mu.Unlock()
+	return tp, nil
+}
+
+// GetMenu implements Resource interface.
+func(cr *CachedResource) GetMenu(ctx context.Context, sym string) (string, error) {
+	k := keyFor(ctx, sym)
+	cr.mu.RLock()
+	s, ok := cr.menus[k]
+	cr.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+	s, err := cr.Resource.GetMenu(ctx, sym)
+	if err != nil {
+		return "", err
+	}
+	cr.mu.Lock()
+	cr.menus[k] = s
+	cr.mu.Unlock()
+	return s, nil
+}
+
+// DescribeFunc implements FuncDescriber interface, if the wrapped resource implements it.
+func(cr *CachedResource) DescribeFunc(sym string) (FuncInfo, error) {
+	fd, ok := cr.Resource.(FuncDescriber)
+	if !ok {
+		return FuncInfo{}, fmt.Errorf("no entry function descriptions in resource: %v", cr.Resource)
+	}
+	return fd.DescribeFunc(sym)
+}
+
+// Invalidate removes the bytecode, templates and menus of the symbol in all languages from the cache.
+func(cr *CachedResource) Invalidate(sym string) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	delete(cr.code, sym)
+	for k := range cr.templates {
+		if k.sym == sym {
+			delete(cr.templates, k)
+		}
+	}
+	for k := range cr.parsed {
+		if k.sym == sym {
+			delete(cr.parsed, k)
+		}
+	}
+	for k := range cr.menus {
+		if k.sym == sym {
+			delete(cr.menus, k)
+		}
+	}
+}
+
+// InvalidateAll empties the cache.
+func(cr *CachedResource) InvalidateAll() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.code = make(map[string][]byte)
+	cr.templates = make(map[cacheKey]string)
+	cr.parsed = make(map[cacheKey]*template.Template)
+	cr.menus = make(map[cacheKey]string)
+}
+
+// String implements the String interface.
+func(cr *CachedResource) String() string {
+	return fmt.Sprintf("cached %v", cr.Resource)
+}
@@ -0,0 +1,173 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"git.defalsify.org/vise.git/lang"
+)
+
+// counts the lookups reaching the wrapped resource.
+type countResource struct {
+	*MemResource
+	count int
+}
+
+func(cr *countResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	cr.count++
+	return cr.MemResource.GetTemplate(ctx, sym)
+}
+
+func(cr *countResource) GetCode(sym string) ([]byte, error) {
+	cr.count++
+	return cr.MemResource.GetCode(sym)
+}
+
+func TestCachedResource(t *testing.T) {
+	mr := NewMemResource()
+	mr.AddTemplate("root", "hello {{.foo}}")
+	mr.AddBytecode("root", []byte{0x00, 0x07})
+	rs := &countResource{MemResource: &mr}
+	cr := NewCachedResource(rs)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		r, err := cr.GetTemplate(ctx, "root")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != "hello {{.foo}}" {
+			t.Fatalf("unexpected template: %s", r)
+		}
+		b, err := cr.GetCode("root")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, []byte{0x00, 0x07}) {
+			t.Fatalf("unexpected code: %x", b)
+		}
+	}
+	if rs.count != 2 {
+		t.Fatalf("expected 2 lookups, got %d", rs.count)
+	}
+
+	b, _ := cr.GetCode("root")
+	_ = append(b, 0x01)
+	b, _ = cr.GetCode("root")
+	if len(b) != 2 {
+		t.Fatalf("cached code changed: %x", b)
+	}
+
+	tp, err := cr.GetParsedTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bytes.NewBuffer(nil)
+	err = tp.Execute(w, map[string]string{"foo": "world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "hello world" {
+		t.Fatalf("unexpected render: %s", w)
+	}
+
+	mr.AddTemplate("root", "goodbye")
+	r, _ := cr.GetTemplate(ctx, "root")
+	if r != "hello {{.foo}}" {
+		t.Fatalf("expected cached template, got: %s", r)
+	}
+	cr.Invalidate("root")
+	r, _ = cr.GetTemplate(ctx, "root")
+	if r != "goodbye" {
+		t.Fatalf("expected updated template, got: %s", r)
+	}
+	tp, err = cr.GetParsedTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Reset()
+	tp.Execute(w, nil)
+	if w.String() != "goodbye" {
+		t.Fatalf("unexpected render: %s", w)
+	}
+}
+
+func TestCachedResourceLanguage(t *testing.T) {
+	fs := newTestFsys()
+	cr := NewCachedResource(NewFsysResource(fs))
+	ctx := context.Background()
+	r, err := cr.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := lang.LanguageFromCode("nor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = context.WithValue(ctx, "Language", ln)
+	rl, err := cr.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == rl {
+		t.Fatalf("expected language variant, got: %s", rl)
+	}
+
+	_, err = cr.GetTemplate(ctx, "nope")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	cr.InvalidateAll()
+	r, err = cr.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != rl {
+		t.Fatalf("expected %s, got %s", rl, r)
+	}
+}
+
+func TestCachedResourceLayers(t *testing.T) {
+	base := NewMemResource()
+	base.AddTemplate("root", "hello base")
+	base.AddMenu("back", "back")
+	tenant := NewMemResource()
+	tenant.AddTemplate("root", "hello tenant")
+	tenant.AddMenu("back", "return")
+	lr := NewLayeredResource().WithLayer("base", &base).WithLayer("tenant", &tenant)
+	cr := NewCachedResource(lr)
+
+	ctx := context.WithValue(context.Background(), "Layers", []string{"base"})
+	r, err := cr.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello base" {
+		t.Fatalf("unexpected template: %s", r)
+	}
+	r, err = cr.GetMenu(ctx, "back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "back" {
+		t.Fatalf("unexpected menu: %s", r)
+	}
+
+	ctx = context.WithValue(context.Background(), "Layers", []string{"tenant", "base"})
+	r, err = cr.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello tenant" {
+		t.Fatalf("expected template of selected layer, got: %s", r)
+	}
+	r, err = cr.GetMenu(ctx, "back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "return" {
+		t.Fatalf("expected menu of selected layer, got: %s", r)
+	}
+}
@@ -1,11 +1,14 @@
 package resource
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"os"
 	"path"
 	"testing"
 
+	"git.defalsify.org/vise.git/bytecode"
 	"git.defalsify.org/vise.git/lang"
 )
 
@@ -124,3 +127,36 @@ func TestResourceMenuLanguage(t *testing.T) {
 		t.Fatalf("expected '%s', got '%s'", menu, r)
 	}
 }
+
+func TestResourceCodeContainer(t *testing.T) {
+	code := []byte{0x00, 0x07}
+	dir := t.TempDir()
+	err := os.WriteFile(path.Join(dir, "foo.bin"), bytecode.Encode(bytecode.Header{VmVersion: bytecode.VM_VERSION}, code), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path.Join(dir, "bar.bin"), bytecode.Encode(bytecode.Header{VmVersion: bytecode.VM_VERSION + 1}, code), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path.Join(dir, "baz.bin"), code, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := NewFsResource(dir)
+	for _, v := range []string{"foo", "baz"} {
+		r, err := rs.GetCode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(r, code) {
+			t.Fatalf("expected %x, got %x", code, r)
+		}
+	}
+	_, err = rs.GetCode("bar")
+	var verr bytecode.VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/lang"
+)
+
+// FsysResource resolves bytecode, templates, menus and static entry function contents from files in an fs.FS.
+//
+// The files are laid out as for FsResource, which makes it possible to serve a resource directory embedded in the executable with go:embed.
+type FsysResource struct {
+	MenuResource
+	fsys fs.FS
+	fns *FuncRegistry
+}
+
+// NewFsysResource creates a new FsysResource reading from the given filesystem.
+func NewFsysResource(fsys fs.FS) *FsysResource {
+	return &FsysResource{
+		fsys: fsys,
+		fns: NewFuncRegistry(),
+	}
+}
+
+// read the file with the given name, preferring the variant for the language in the context.
+//
+// The language code is inserted before the suffix.
+func(fr *FsysResource) read(ctx context.Context, name string, suffix string) ([]byte, error) {
+	fp := name + suffix
+	v := ctx.Value("Language")
+	if v != nil {
+		lang := v.(lang.Language)
+		fpl := name + "_" + lang.Code + suffix
+		r, err := fs.ReadFile(fr.fsys, fpl)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return r, err
+		}
+	}
+	return fs.ReadFile(fr.fsys, fp)
+}
+
+// GetTemplate implements Resource interface.
+//
+// The template is read from <sym>_<lang> if a language is set in the context and the file exists, and from <sym> otherwise.
+func(fr *FsysResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	r, err := fr.read(ctx, sym, "")
+	if err != nil {
+		return "", fmt.Errorf("failed getting template for sym '%s': %v", sym, err)
+	}
+	return strings.TrimSpace(string(r)), nil
+}
+
+// GetCode implements Resource interface.
+//
+// The bytecode is read from <sym>.bin. The container header is removed, if present. Fails if the bytecode was assembled for a different vm version.
+func(fr *FsysResource) GetCode(sym string) ([]byte, error) {
+	b, err := fs.ReadFile(fr.fsys, sym + ".bin")
+	if err != nil {
+		return nil, err
+	}
+	b, err = bytecode.Code(b)
+	if err != nil {
+		return nil, fmt.Errorf("bytecode for sym '%s': %w", sym, err)
+	}
+	return b, nil
+}
+
+// GetMenu implements Resource interface.
+//
+// The menu label is read from <sym>_menu_<lang> if a language is set in the context and the file exists, and from <sym>_menu otherwise. If neither exists, the symbol itself is returned.
+func(fr *FsysResource) GetMenu(ctx context.Context, sym string) (string, error) {
+	r, err := fr.read(ctx, sym + "_menu", "")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return sym, nil
+		}
+		return "", fmt.Errorf("failed getting template for sym '%s': %v", sym, err)
+	}
+	return strings.TrimSpace(string(r)), nil
+}
+
+// AddLocalFunc associates an entry function with a symbol, taking precedence over static contents in files.
+func(fr *FsysResource) AddLocalFunc(sym string, fn EntryFunc) {
+	fr.fns.Set(sym, fn)
+}
+
+// WithFuncRegistry sets the registry of entry functions taking precedence over static contents in files.
+//
+// The registry replaces all entry functions added before, and may be shared with other resources.
+func(fr *FsysResource) WithFuncRegistry(fns *FuncRegistry) *FsysResource {
+	fr.fns = fns
+	return fr
+}
+
+// FuncRegistry returns the registry of entry functions used by the resource.
+func(fr *FsysResource) FuncRegistry() *FuncRegistry {
+	return fr.fns
+}
+
+// FuncFor implements Resource interface.
+//
+// Entry functions in the registry are returned first. Otherwise, if the file <sym>.txt exists, an entry function returning its contents is returned. The contents are read from <sym>_<lang>.txt if a language is set in the context and the file exists.
+//
+// Fails with UnknownSymbolError if neither exists.
+func(fr *FsysResource) FuncFor(sym string) (EntryFunc, error) {
+	info, err := fr.DescribeFunc(sym)
+	if err != nil {
+		return nil, err
+	}
+	return info.Func, nil
+}
+
+// DescribeFunc implements FuncDescriber interface.
+//
+// Static contents in files are described as such.
+func(fr *FsysResource) DescribeFunc(sym string) (FuncInfo, error) {
+	info, err := fr.fns.DescribeFunc(sym)
+	if err == nil {
+		return info, nil
+	}
+	_, err = fs.Stat(fr.fsys, sym + ".txt")
+	if err != nil {
+		return FuncInfo{}, &UnknownSymbolError{Sym: sym}
+	}
+	return FuncInfo{
+		Sym: sym,
+		Description: "static content of " + sym + ".txt",
+		Func: fr.getFunc,
+	}, nil
+}
+
+// String implements the String interface.
+func(fr *FsysResource) String() string {
+	return fmt.Sprintf("fs.FS resource: %v", fr.fsys)
+}
+
+// entry function returning the static contents of <sym>.txt.
+func(fr *FsysResource) getFunc(ctx context.Context, sym string, input []byte) (Result, error) {
+	r, err := fr.read(ctx, sym, ".txt")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed getting data for sym '%s': %v", sym, err)
+	}
+	return Result{
+		Content: strings.TrimSpace(string(r)),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"git.defalsify.org/vise.git/bytecode"
+	"git.defalsify.org/vise.git/lang"
+)
+
+func newTestFsys() fstest.MapFS {
+	return fstest.MapFS{
+		"root": &fstest.MapFile{Data: []byte("hello {{.foo}}\n")},
+		"root_nor": &fstest.MapFile{Data: []byte("hei {{.foo}}\n")},
+		"root.bin": &fstest.MapFile{Data: []byte{0x00, 0x07}},
+		"next.bin": &fstest.MapFile{Data: bytecode.Encode(bytecode.Header{VmVersion: bytecode.VM_VERSION}, []byte{0x00, 0x07})},
+		"old.bin": &fstest.MapFile{Data: bytecode.Encode(bytecode.Header{VmVersion: bytecode.VM_VERSION + 1}, []byte{0x00, 0x07})},
+		"next_menu": &fstest.MapFile{Data: []byte("go on")},
+		"next_menu_nor": &fstest.MapFile{Data: []byte("videre")},
+		"foo.txt": &fstest.MapFile{Data: []byte("world")},
+		"foo_nor.txt": &fstest.MapFile{Data: []byte("verden")},
+		"bar_nor.txt": &fstest.MapFile{Data: []byte("bar")},
+	}
+}
+
+func TestFsysTemplate(t *testing.T) {
+	rs := NewFsysResource(newTestFsys())
+	ctx := context.Background()
+	r, err := rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello {{.foo}}" {
+		t.Fatalf("unexpected template: '%s'", r)
+	}
+	_, err = rs.GetTemplate(ctx, "nope")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	l, err := lang.LanguageFromCode("nor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = context.WithValue(ctx, "Language", l)
+	r, err = rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hei {{.foo}}" {
+		t.Fatalf("unexpected template: '%s'", r)
+	}
+}
+
+func TestFsysMenu(t *testing.T) {
+	rs := NewFsysResource(newTestFsys())
+	ctx := context.Background()
+	r, err := rs.GetMenu(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "go on" {
+		t.Fatalf("unexpected menu: '%s'", r)
+	}
+	r, err = rs.GetMenu(ctx, "back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "back" {
+		t.Fatalf("unexpected menu: '%s'", r)
+	}
+
+	l, err := lang.LanguageFromCode("nor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = context.WithValue(ctx, "Language", l)
+	r, err = rs.GetMenu(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "videre" {
+		t.Fatalf("unexpected menu: '%s'", r)
+	}
+}
+
+func TestFsysCode(t *testing.T) {
+	rs := NewFsysResource(newTestFsys())
+	for _, v := range []string{"root", "next"} {
+		r, err := rs.GetCode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(r, []byte{0x00, 0x07}) {
+			t.Fatalf("unexpected code for %s: %x", v, r)
+		}
+	}
+	_, err := rs.GetCode("old")
+	var verr bytecode.VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+	_, err = rs.GetCode("nope")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFsysFunc(t *testing.T) {
+	rs := NewFsysResource(newTestFsys())
+	ctx := context.Background()
+	fn, err := rs.FuncFor("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := fn(ctx, "foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "world" {
+		t.Fatalf("unexpected content: '%s'", r.Content)
+	}
+
+	l, err := lang.LanguageFromCode("nor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = fn(context.WithValue(ctx, "Language", l), "foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "verden" {
+		t.Fatalf("unexpected content: '%s'", r.Content)
+	}
+
+	_, err = rs.FuncFor("bar")
+	if err == nil {
+		t.Fatalf("expected error for language only entry")
+	}
+	rs.AddLocalFunc("bar", getTestFunc)
+	_, err = rs.FuncFor("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,464 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"git.defalsify.org/vise.git/lang"
+	"git.defalsify.org/vise.git/state"
+)
+
+const (
+	// Limit of the response body read by an HTTP entry function, if no smaller limit follows from its configuration.
+	HTTP_RESPONSE_LIMIT = 1 << 20
+)
+
+// HttpCondition maps a condition on the response of an HTTP entry function to flag changes.
+//
+// All criteria set in the condition must match. Flags are given by number, or by name if a flag registry is used.
+type HttpCondition struct {
+	Status int `json:"status,omitempty"` // HTTP status code of the response. Zero matches any status.
+	Path string `json:"path,omitempty"` // JSON path to a value in the response.
+	Value string `json:"value,omitempty"` // Value expected at the path. If empty, the value must be present and not false, null or an empty string.
+	Set []string `json:"set,omitempty"` // Flags to set if the condition matches.
+	Reset []string `json:"reset,omitempty"` // Flags to reset if the condition matches.
+}
+
+// HttpFuncConfig defines an entry function performing an HTTP request.
+//
+// Url, Headers and Body are text/template templates, executed with HttpRequestData. Values are escaped for where they are used:
+//
+// In the Url template, all values are escaped for use as a path segment or query value.
+//
+// In the Body template, values are escaped for use in a JSON string, so they must be placed within quotes. If the Content-Type header is application/x-www-form-urlencoded, they are escaped as form values instead.
+//
+// Header values containing control characters are rejected.
+type HttpFuncConfig struct {
+	Description string `json:"description,omitempty"`
+	Method string `json:"method,omitempty"` // Defaults to GET.
+	Url string `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body string `json:"body,omitempty"`
+	Path string `json:"path,omitempty"` // JSON path to the content value in the response. If empty, the content is the response body.
+	Timeout int `json:"timeout,omitempty"` // Request timeout in seconds. Zero for no timeout other than that of the context.
+	MaxSize uint32 `json:"max_size,omitempty"` // Expected maximum size of the content. If no path is set, responses larger than this fail. Otherwise responses are limited to HTTP_RESPONSE_LIMIT.
+	MaxAge int `json:"max_age,omitempty"` // Seconds after which a loaded value should be refreshed.
+	Conditions []HttpCondition `json:"conditions,omitempty"`
+}
+
+// HttpRequestData is the data the request templates of an HTTP entry function are executed with.
+type HttpRequestData struct {
+	Sym string
+	Input string
+	SessionId string
+	Language string // Language code from the execution context, if set.
+}
+
+// HttpFunc is an entry function performing an HTTP request defined by a HttpFuncConfig.
+//
+// The content of the result is the value at the configured JSON path of the response. Its Status is the HTTP status code of the response.
+//
+// A response with a status code outside the 2xx range fails, unless it matches one of the conditions.
+type HttpFunc struct {
+	sym string
+	cfg HttpFuncConfig
+	client *http.Client
+	url *template.Template
+	body *template.Template
+	headers map[string]*template.Template
+	set [][]uint32
+	reset [][]uint32
+}
+
+// NewHttpFunc creates a new HttpFunc for the symbol from the configuration.
+//
+// Flag names in conditions are resolved with the flag registry. The registry may be nil if all flags are given by number.
+func NewHttpFunc(sym string, cfg HttpFuncConfig, flags *state.FlagRegistry) (*HttpFunc, error) {
+	var err error
+	if cfg.Url == "" {
+		return nil, fmt.Errorf("url missing for http func: %s", sym)
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	hf := &HttpFunc{
+		sym: sym,
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
+		},
+		headers: make(map[string]*template.Template),
+	}
+	hf.url, err = template.New("url").Option("missingkey=error").Parse(cfg.Url)
+	if err != nil {
+		return nil, fmt.Errorf("url template for http func %s: %v", sym, err)
+	}
+	hf.body, err = template.New("body").Option("missingkey=error").Parse(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("body template for http func %s: %v", sym, err)
+	}
+	for k, v := range cfg.Headers {
+		hf.headers[k], err = template.New(k).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("header template '%s' for http func %s: %v", k, sym, err)
+		}
+	}
+	for i, v := range cfg.Conditions {
+		set, err := resolveFlags(v.Set, flags)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d for http func %s: %v", i, sym, err)
+		}
+		reset, err := resolveFlags(v.Reset, flags)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d for http func %s: %v", i, sym, err)
+		}
+		hf.set = append(hf.set, set)
+		hf.reset = append(hf.reset, reset)
+	}
+	return hf, nil
+}
+
+// WithClient sets the HTTP client to perform requests with.
+func(hf *HttpFunc) WithClient(client *http.Client) *HttpFunc {
+	hf.client = client
+	return hf
+}
+
+// Info returns the description of the entry function, for use with FuncRegistry.
+func(hf *HttpFunc) Info() FuncInfo {
+	var flags []uint32
+	seen := make(map[uint32]bool)
+	add := func(l []uint32) {
+		for _, v := range l {
+			if !seen[v] {
+				flags = append(flags, v)
+				seen[v] = true
+			}
+		}
+	}
+	for i := range hf.set {
+		add(hf.set[i])
+		add(hf.reset[i])
+	}
+	sort.Slice(flags, func(i int, j int) bool {
+		return flags[i] < flags[j]
+	})
+	return FuncInfo{
+		Sym: hf.sym,
+		Description: hf.cfg.Description,
+		MaxSize: hf.cfg.MaxSize,
+		MaxAge: time.Duration(hf.cfg.MaxAge) * time.Second,
+		Flags: flags,
+		Func: hf.Get,
+	}
+}
+
+// Get performs the request and evaluates the response.
+//
+// It implements EntryFunc.
+func(hf *HttpFunc) Get(ctx context.Context, sym string, input []byte) (Result, error) {
+	var r Result
+	data := HttpRequestData{
+		Sym: sym,
+		Input: string(input),
+	}
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if ok {
+		data.SessionId = sessionId
+	}
+	ln, ok := ctx.Value("Language").(lang.Language)
+	if ok {
+		data.Language = ln.Code
+	}
+
+	req, err := hf.request(ctx, data)
+	if err != nil {
+		return r, err
+	}
+	Logg.DebugCtxf(ctx, "http func request", "sym", sym, "method", req.Method, "url", req.URL)
+	rsp, err := hf.client.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer rsp.Body.Close()
+	limit := hf.limit()
+	b, err := io.ReadAll(io.LimitReader(rsp.Body, limit + 1))
+	if err != nil {
+		return r, err
+	}
+	if int64(len(b)) > limit {
+		return r, fmt.Errorf("http func %s: response exceeds %d bytes", sym, limit)
+	}
+	r.Status = rsp.StatusCode
+
+	var doc interface{}
+	if len(b) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		err = dec.Decode(&doc)
+		if err != nil {
+			Logg.DebugCtxf(ctx, "http func response not json", "sym", sym, "err", err)
+			doc = nil
+		}
+	}
+
+	var matched bool
+	for i, v := range hf.cfg.Conditions {
+		if !v.match(rsp.StatusCode, doc) {
+			continue
+		}
+		matched = true
+		r.FlagSet = append(r.FlagSet, hf.set[i]...)
+		r.FlagReset = append(r.FlagReset, hf.reset[i]...)
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		if !matched {
+			return r, fmt.Errorf("http func %s: unexpected status: %s", sym, rsp.Status)
+		}
+		return r, nil
+	}
+
+	if hf.cfg.Path == "" {
+		r.Content = string(b)
+		return r, nil
+	}
+	if doc == nil {
+		return r, fmt.Errorf("http func %s: response is not json", sym)
+	}
+	val, ok := jsonPath(doc, hf.cfg.Path)
+	if !ok {
+		if matched {
+			return r, nil
+		}
+		return r, fmt.Errorf("http func %s: path '%s' not found in response", sym, hf.cfg.Path)
+	}
+	r.Content = jsonString(val)
+	return r, nil
+}
+
+// build the request from the templates.
+func(hf *HttpFunc) request(ctx context.Context, data HttpRequestData) (*http.Request, error) {
+	url := bytes.NewBuffer(nil)
+	err := hf.url.Execute(url, data.escaped())
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(nil)
+	if hf.form() {
+		err = hf.body.Execute(body, data.escaped())
+	} else {
+		err = hf.body.Execute(body, data.jsonEscaped())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rd io.Reader
+	if body.Len() > 0 {
+		rd = body
+	}
+	req, err := http.NewRequestWithContext(ctx, hf.cfg.Method, url.String(), rd)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hf.headers {
+		h := bytes.NewBuffer(nil)
+		err = v.Execute(h, data)
+		if err != nil {
+			return nil, err
+		}
+		if strings.IndexFunc(h.String(), isControl) > -1 {
+			return nil, fmt.Errorf("http func %s: control character in header '%s'", hf.sym, k)
+		}
+		req.Header.Set(k, h.String())
+	}
+	return req, nil
+}
+
+// maximum number of bytes to read from the response body.
+func(hf *HttpFunc) limit() int64 {
+	if hf.cfg.MaxSize > 0 && hf.cfg.Path == "" {
+		return int64(hf.cfg.MaxSize)
+	}
+	return HTTP_RESPONSE_LIMIT
+}
+
+// true if the body is sent as form values.
+func(hf *HttpFunc) form() bool {
+	for k, v := range hf.cfg.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			return strings.HasPrefix(strings.ToLower(v), "application/x-www-form-urlencoded")
+		}
+	}
+	return false
+}
+
+// true for characters not allowed in header values.
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\t') || r == 0x7f
+}
+
+// copy of the data with all values escaped for use in a url.
+//
+// Values are query escaped, with spaces as %20 instead of +, so that they are safe both as path segment and as query value.
+func(data HttpRequestData) escaped() HttpRequestData {
+	esc := func(s string) string {
+		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+	return HttpRequestData{
+		Sym: esc(data.Sym),
+		Input: esc(data.Input),
+		SessionId: esc(data.SessionId),
+		Language: esc(data.Language),
+	}
+}
+
+// copy of the data with all values escaped for use in a JSON string.
+func(data HttpRequestData) jsonEscaped() HttpRequestData {
+	esc := func(s string) string {
+		b := bytes.NewBuffer(nil)
+		enc := json.NewEncoder(b)
+		enc.SetEscapeHTML(false)
+		enc.Encode(s)
+		r := strings.TrimSuffix(b.String(), "\n")
+		return r[1:len(r)-1]
+	}
+	return HttpRequestData{
+		Sym: esc(data.Sym),
+		Input: esc(data.Input),
+		SessionId: esc(data.SessionId),
+		Language: esc(data.Language),
+	}
+}
+
+// true if the response matches the condition.
+func(hc HttpCondition) match(status int, doc interface{}) bool {
+	if hc.Status > 0 && hc.Status != status {
+		return false
+	}
+	if hc.Path == "" {
+		return true
+	}
+	val, ok := jsonPath(doc, hc.Path)
+	if !ok {
+		return false
+	}
+	if hc.Value != "" {
+		return jsonString(val) == hc.Value
+	}
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+// ReadHttpFuncs reads HTTP entry function configurations as a JSON object keyed by symbol, and adds the functions to the registry.
+//
+// Flag names in conditions are resolved with the flag registry, which may be nil if all flags are given by number.
+func ReadHttpFuncs(r io.Reader, fns *FuncRegistry, flags *state.FlagRegistry) error {
+	var cfgs map[string]HttpFuncConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&cfgs)
+	if err != nil {
+		return fmt.Errorf("http func config: %v", err)
+	}
+	var syms []string
+	for k := range cfgs {
+		syms = append(syms, k)
+	}
+	sort.Strings(syms)
+	for _, k := range syms {
+		hf, err := NewHttpFunc(k, cfgs[k], flags)
+		if err != nil {
+			return err
+		}
+		err = fns.Register(hf.Info())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flag numbers for the given flag numbers or names.
+func resolveFlags(names []string, flags *state.FlagRegistry) ([]uint32, error) {
+	var r []uint32
+	for _, v := range names {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err == nil {
+			r = append(r, uint32(n))
+			continue
+		}
+		if flags == nil {
+			return nil, fmt.Errorf("flag name '%s' without flag registry", v)
+		}
+		flag, err := flags.Flag(v)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, flag)
+	}
+	return r, nil
+}
+
+// value at the dot separated path in the decoded json document.
+//
+// Path elements of arrays are indices.
+func jsonPath(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	for _, k := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			val, ok := v[k]
+			if !ok {
+				return nil, false
+			}
+			cur = val
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// string representation of a decoded json value.
+//
+// Objects and arrays are returned as compact json.
+func jsonString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,344 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/state"
+)
+
+func newTestHttpServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/account/create":
+			b, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || r.Header.Get("X-Session") != "+254700000000" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"ok": true, "result": {"trackingId": "%s", "ids": [13, 42]}}`, strings.TrimSpace(string(b)))
+		case "/track/foo":
+			w.Write([]byte(`{"ok": true, "result": {"status": "SUCCESS"}}`))
+		case "/track/bar":
+			w.Write([]byte(`{"ok": true, "result": {"status": "PENDING"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ok": false}`))
+		}
+	}))
+}
+
+func TestHttpFunc(t *testing.T) {
+	srv := newTestHttpServer()
+	defer srv.Close()
+	flags := state.NewFlagRegistry()
+	flagSuccess := flags.MustAdd("account_success")
+	flagPending := flags.MustAdd("account_pending")
+
+	cfg := HttpFuncConfig{
+		Url: srv.URL + "/track/{{.Input}}",
+		Path: "result.status",
+		Conditions: []HttpCondition{
+			{Path: "result.status", Value: "SUCCESS", Set: []string{"account_success"}, Reset: []string{"account_pending"}},
+			{Path: "result.status", Value: "PENDING", Set: []string{"account_pending"}},
+			{Status: http.StatusNotFound, Reset: []string{"account_success", "9"}},
+		},
+	}
+	hf, err := NewHttpFunc("track", cfg, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	r, err := hf.Get(ctx, "track", []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "SUCCESS" || r.Status != http.StatusOK {
+		t.Fatalf("unexpected result: %v", r)
+	}
+	if len(r.FlagSet) != 1 || r.FlagSet[0] != flagSuccess || len(r.FlagReset) != 1 || r.FlagReset[0] != flagPending {
+		t.Fatalf("unexpected flags: %v", r)
+	}
+
+	r, err = hf.Get(ctx, "track", []byte("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "PENDING" || len(r.FlagSet) != 1 || r.FlagSet[0] != flagPending || len(r.FlagReset) != 0 {
+		t.Fatalf("unexpected result: %v", r)
+	}
+
+	r, err = hf.Get(ctx, "track", []byte("baz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != http.StatusNotFound || r.Content != "" || len(r.FlagReset) != 2 || r.FlagReset[1] != 9 {
+		t.Fatalf("unexpected result: %v", r)
+	}
+
+	info := hf.Info()
+	if len(info.Flags) != 2 || info.Flags[0] != flagSuccess || info.Flags[1] != flagPending {
+		t.Fatalf("unexpected flags in info: %v", info.Flags)
+	}
+
+	cfg.Conditions = cfg.Conditions[:2]
+	hf, err = NewHttpFunc("track", cfg, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = hf.Get(ctx, "track", []byte("baz"))
+	if err == nil {
+		t.Fatalf("expected error on unmatched status")
+	}
+}
+
+func TestHttpFuncRequest(t *testing.T) {
+	srv := newTestHttpServer()
+	defer srv.Close()
+	cfg := HttpFuncConfig{
+		Method: http.MethodPost,
+		Url: srv.URL + "/account/create",
+		Headers: map[string]string{
+			"X-Session": "{{.SessionId}}",
+		},
+		Body: "{{.Sym}}-{{.Input}}",
+		Path: "result.ids.1",
+	}
+	hf, err := NewHttpFunc("create", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), "SessionId", "+254700000000")
+	r, err := hf.Get(ctx, "create", []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "42" {
+		t.Fatalf("unexpected content: %s", r.Content)
+	}
+
+	_, err = hf.Get(context.Background(), "create", []byte("1"))
+	if err == nil {
+		t.Fatalf("expected error on bad request")
+	}
+
+	cfg.Path = "result"
+	hf, err = NewHttpFunc("create", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = hf.Get(ctx, "create", []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != `{"ids":[13,42],"trackingId":"create-1"}` {
+		t.Fatalf("unexpected content: %s", r.Content)
+	}
+
+	cfg.Path = "result.nope"
+	hf, err = NewHttpFunc("create", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = hf.Get(ctx, "create", []byte("1"))
+	if err == nil {
+		t.Fatalf("expected error on missing path")
+	}
+}
+
+func TestHttpFuncUrlEscape(t *testing.T) {
+	cfg := HttpFuncConfig{
+		Url: "https://example.com/track/{{.Input}}?lang={{.Language}}",
+		Body: "{{.Input}}",
+	}
+	hf, err := NewHttpFunc("track", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, v := range []string{"1?admin=1", "../x", "a b&c=d#e"} {
+		data := HttpRequestData{
+			Sym: "track",
+			Input: v,
+		}
+		req, err := hf.request(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.URL.Path != "/track/" + v || strings.Count(req.URL.EscapedPath(), "/") != 2 {
+			t.Fatalf("expected input as single path segment, got path %s for %s", req.URL.EscapedPath(), v)
+		}
+		if req.URL.Query().Get("lang") != "" || len(req.URL.Query()) != 1 || req.URL.Fragment != "" {
+			t.Fatalf("input %s changed query: %s", v, req.URL)
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v {
+			t.Fatalf("expected unescaped input in body, got %s", b)
+		}
+	}
+}
+
+func TestReadHttpFuncs(t *testing.T) {
+	srv := newTestHttpServer()
+	defer srv.Close()
+	cfgs := map[string]HttpFuncConfig{
+		"track": HttpFuncConfig{
+			Description: "account status",
+			Url: srv.URL + "/track/{{.Input}}",
+			Path: "result.status",
+			MaxSize: 10,
+			Conditions: []HttpCondition{
+				{Path: "ok", Set: []string{"has_account"}},
+			},
+		},
+	}
+	b, err := json.Marshal(cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fns := NewFuncRegistry()
+	err = ReadHttpFuncs(strings.NewReader(string(b)), fns, nil)
+	if err == nil {
+		t.Fatalf("expected error on flag name without registry")
+	}
+
+	flags := state.NewFlagRegistry()
+	flag := flags.MustAdd("has_account")
+	err = ReadHttpFuncs(strings.NewReader(string(b)), fns, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := fns.DescribeFunc("track")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Description != "account status" || info.MaxSize != 10 || len(info.Flags) != 1 || info.Flags[0] != flag {
+		t.Fatalf("unexpected info: %v", info)
+	}
+	r, err := info.Func(context.Background(), "track", []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content != "SUCCESS" || len(r.FlagSet) != 1 {
+		t.Fatalf("unexpected result: %v", r)
+	}
+
+	err = ReadHttpFuncs(strings.NewReader(`{"foo": {"url": "x", "nope": 1}}`), fns, nil)
+	if err == nil {
+		t.Fatalf("expected error on unknown field")
+	}
+}
+
+func TestHttpFuncBodyEscape(t *testing.T) {
+	cfg := HttpFuncConfig{
+		Method: http.MethodPost,
+		Url: "https://example.com/account",
+		Headers: map[string]string{
+			"X-Input": "{{.Input}}",
+		},
+		Body: `{"name": "{{.Input}}", "admin": false}`,
+	}
+	hf, err := NewHttpFunc("account", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, v := range []string{`x", "admin": true, "y": "`, `x"}`, "a\\\"b <&>"} {
+		data := HttpRequestData{
+			Sym: "account",
+			Input: v,
+		}
+		req, err := hf.request(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		err = json.NewDecoder(req.Body).Decode(&doc)
+		if err != nil {
+			t.Fatalf("input %s: invalid json body: %v", v, err)
+		}
+		if len(doc) != 2 || doc["name"] != v || doc["admin"] != false {
+			t.Fatalf("input %s changed body: %v", v, doc)
+		}
+	}
+
+	for _, v := range []string{"x\r\nX-Admin: 1", "x\ny", "x\x00"} {
+		data := HttpRequestData{
+			Sym: "account",
+			Input: v,
+		}
+		_, err = hf.request(ctx, data)
+		if err == nil {
+			t.Fatalf("expected error for control character in header: %q", v)
+		}
+	}
+
+	cfg.Headers = map[string]string{
+		"content-type": "application/x-www-form-urlencoded",
+	}
+	cfg.Body = "name={{.Input}}&admin=0"
+	hf, err = NewHttpFunc("account", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := HttpRequestData{
+		Sym: "account",
+		Input: "x&admin=1",
+	}
+	req, err := hf.request(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = req.ParseForm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.PostForm.Get("name") != "x&admin=1" || req.PostForm.Get("admin") != "0" {
+		t.Fatalf("input changed form: %v", req.PostForm)
+	}
+}
+
+func TestHttpFuncLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 16)))
+	}))
+	defer srv.Close()
+	cfg := HttpFuncConfig{
+		Url: srv.URL,
+		MaxSize: 16,
+	}
+	hf, err := NewHttpFunc("foo", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), "SessionId", 42)
+	ctx = context.WithValue(ctx, "Language", "nor")
+	r, err := hf.Get(ctx, "foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Content) != 16 {
+		t.Fatalf("unexpected content: %s", r.Content)
+	}
+
+	cfg.MaxSize = 15
+	hf, err = NewHttpFunc("foo", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = hf.Get(ctx, "foo", nil)
+	if err == nil {
+		t.Fatalf("expected error on response exceeding max size")
+	}
+}
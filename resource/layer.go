@@ -0,0 +1,187 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+)
+
+// LayeredResource resolves symbols from a stack of named resources, where each layer overrides the layers below it.
+//
+// Templates, menus, bytecode, entry functions and their descriptions are looked up top-down, and the first layer resolving a symbol is used. A layer resolves a symbol if its lookup does not fail, and for menus, if the label differs from the symbol. A failing layer does not hide the layers below it. If no layer resolves the symbol, the result of the bottom layer is returned.
+//
+// The layer order can be selected per session by setting "Layers" in the context to the names of the layers to use, top first. Bytecode lookups and function descriptions have no context, and always use the default order, so a layer selected for a session overrides templates, menus and entry functions, but not bytecode. Select returns a resource with a fixed order, which can be used instead if the bytecode also differs between sessions.
+type LayeredResource struct {
+	layers map[string]Resource
+	order []string
+}
+
+// NewLayeredResource creates a new LayeredResource without layers.
+func NewLayeredResource() *LayeredResource {
+	return &LayeredResource{
+		layers: make(map[string]Resource),
+	}
+}
+
+// WithLayer adds a named layer on top of the existing layers.
+//
+// Panics if a layer with the same name already exists.
+func(lr *LayeredResource) WithLayer(name string, rs Resource) *LayeredResource {
+	_, ok := lr.layers[name]
+	if ok {
+		panic(fmt.Sprintf("duplicate layer: %s", name))
+	}
+	lr.layers[name] = rs
+	lr.order = append([]string{name}, lr.order...)
+	return lr
+}
+
+// Layers returns the names of the layers in default order, top first.
+func(lr *LayeredResource) Layers() []string {
+	return append([]string{}, lr.order...)
+}
+
+// Select returns a LayeredResource sharing the layers, with the given layers as default order, top first.
+func(lr *LayeredResource) Select(names ...string) (*LayeredResource, error) {
+	_, err := lr.resolve(names)
+	if err != nil {
+		return nil, err
+	}
+	return &LayeredResource{
+		layers: lr.layers,
+		order: append([]string{}, names...),
+	}, nil
+}
+
+// the layers to use, top first.
+func(lr *LayeredResource) resolve(names []string) ([]Resource, error) {
+	var r []Resource
+	for _, v := range names {
+		rs, ok := lr.layers[v]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource layer: %s", v)
+		}
+		r = append(r, rs)
+	}
+	return r, nil
+}
+
+// the layers to use for the session in the context.
+func(lr *LayeredResource) forCtx(ctx context.Context) ([]Resource, error) {
+	v := ctx.Value("Layers")
+	if v == nil {
+		return lr.resolve(lr.order)
+	}
+	names, ok := v.([]string)
+	if !ok {
+		return nil, fmt.Errorf("invalid layers in context: %v", v)
+	}
+	return lr.resolve(names)
+}
+
+// GetTemplate implements Resource interface.
+func(lr *LayeredResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	layers, err := lr.forCtx(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range layers {
+		var r string
+		r, err = v.GetTemplate(ctx, sym)
+		if err == nil {
+			return r, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no resource layers for template sym: %s", sym)
+	}
+	return "", err
+}
+
+// GetMenu implements Resource interface.
+func(lr *LayeredResource) GetMenu(ctx context.Context, sym string) (string, error) {
+	layers, err := lr.forCtx(ctx)
+	if err != nil {
+		return "", err
+	}
+	r := sym
+	for _, v := range layers {
+		r, err = v.GetMenu(ctx, sym)
+		if err == nil && r != sym {
+			return r, nil
+		}
+	}
+	return r, err
+}
+
+// GetCode implements Resource interface.
+//
+// Always uses the default layer order.
+func(lr *LayeredResource) GetCode(sym string) ([]byte, error) {
+	layers, err := lr.resolve(lr.order)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range layers {
+		var r []byte
+		r, err = v.GetCode(sym)
+		if err == nil {
+			return r, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no resource layers for code sym: %s", sym)
+	}
+	return nil, err
+}
+
+// FuncFor implements Resource interface.
+//
+// Fails with UnknownSymbolError if no layer has an entry function for the symbol. Otherwise, the returned entry function resolves the symbol top-down in the layer order of the context it is called with.
+func(lr *LayeredResource) FuncFor(sym string) (EntryFunc, error) {
+	for _, v := range lr.layers {
+		_, err := v.FuncFor(sym)
+		if err == nil {
+			return lr.dispatch, nil
+		}
+	}
+	return nil, &UnknownSymbolError{Sym: sym}
+}
+
+// DescribeFunc implements FuncDescriber interface.
+//
+// Always uses the default layer order. Layers not implementing FuncDescriber are skipped. Fails with UnknownSymbolError if no layer describes the symbol. The function of the description resolves the symbol in the same way as the function returned by FuncFor.
+func(lr *LayeredResource) DescribeFunc(sym string) (FuncInfo, error) {
+	layers, err := lr.resolve(lr.order)
+	if err != nil {
+		return FuncInfo{}, err
+	}
+	for _, v := range layers {
+		fd, ok := v.(FuncDescriber)
+		if !ok {
+			continue
+		}
+		info, err := fd.DescribeFunc(sym)
+		if err == nil {
+			info.Func = lr.dispatch
+			return info, nil
+		}
+	}
+	return FuncInfo{}, &UnknownSymbolError{Sym: sym}
+}
+
+// call the entry function of the topmost layer resolving the symbol.
+//
+// Fails with UnknownSymbolError if no layer in the order of the context has an entry function for the symbol.
+func(lr *LayeredResource) dispatch(ctx context.Context, sym string, input []byte) (Result, error) {
+	layers, err := lr.forCtx(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, v := range layers {
+		fn, err := v.FuncFor(sym)
+		if err == nil {
+			return fn(ctx, sym, input)
+		}
+	}
+	return Result{}, &UnknownSymbolError{Sym: sym}
+}
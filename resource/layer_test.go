@@ -0,0 +1,248 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestLayers() *LayeredResource {
+	base := NewMemResource()
+	base.AddTemplate("root", "welcome")
+	base.AddTemplate("foo", "foo")
+	base.AddMenu("next", "continue")
+	base.AddBytecode("root", []byte{0x00, 0x07})
+	base.AddEntryFunc("bar", func(ctx context.Context, sym string, input []byte) (Result, error) {
+		return Result{Content: "base bar"}, nil
+	})
+
+	brand := NewMemResource()
+	brand.AddTemplate("root", "welcome to brand")
+	brand.AddMenu("next", "go on")
+	brand.AddBytecode("root", []byte{0x00, 0x07, 0x00, 0x07})
+	brand.AddEntryFunc("bar", func(ctx context.Context, sym string, input []byte) (Result, error) {
+		return Result{Content: "brand bar"}, nil
+	})
+
+	return NewLayeredResource().WithLayer("base", base).WithLayer("brand", brand)
+}
+
+func TestLayeredResource(t *testing.T) {
+	rs := newTestLayers()
+	ctx := context.Background()
+	if len(rs.Layers()) != 2 || rs.Layers()[0] != "brand" {
+		t.Fatalf("unexpected layers: %v", rs.Layers())
+	}
+
+	r, err := rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "welcome to brand" {
+		t.Fatalf("unexpected template: %s", r)
+	}
+	r, err = rs.GetTemplate(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "foo" {
+		t.Fatalf("unexpected template: %s", r)
+	}
+	_, err = rs.GetTemplate(ctx, "nope")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	r, err = rs.GetMenu(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "go on" {
+		t.Fatalf("unexpected menu: %s", r)
+	}
+	r, err = rs.GetMenu(ctx, "back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "back" {
+		t.Fatalf("unexpected menu: %s", r)
+	}
+
+	code, err := rs.GetCode("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 4 {
+		t.Fatalf("unexpected code: %x", code)
+	}
+
+	fn, err := rs.FuncFor("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := fn(ctx, "bar", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "brand bar" {
+		t.Fatalf("unexpected content: %s", res.Content)
+	}
+	_, err = rs.FuncFor("nope")
+	var uerr *UnknownSymbolError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected unknown symbol error, got %v", err)
+	}
+	_, err = fn(ctx, "nope", nil)
+	if !errors.As(err, &uerr) || uerr.Sym != "nope" {
+		t.Fatalf("expected unknown symbol error, got %v", err)
+	}
+}
+
+func TestLayeredResourceContext(t *testing.T) {
+	rs := newTestLayers()
+	ctx := context.WithValue(context.Background(), "Layers", []string{"base"})
+
+	r, err := rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "welcome" {
+		t.Fatalf("unexpected template: %s", r)
+	}
+	r, err = rs.GetMenu(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "continue" {
+		t.Fatalf("unexpected menu: %s", r)
+	}
+	fn, err := rs.FuncFor("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := fn(ctx, "bar", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "base bar" {
+		t.Fatalf("unexpected content: %s", res.Content)
+	}
+
+	ctx = context.WithValue(context.Background(), "Layers", []string{"nope"})
+	_, err = rs.GetTemplate(ctx, "root")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestLayeredResourceSelect(t *testing.T) {
+	rs := newTestLayers()
+	rss, err := rs.Select("base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := rss.GetCode("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, []byte{0x00, 0x07}) {
+		t.Fatalf("unexpected code: %x", code)
+	}
+	_, err = rs.Select("brand", "nope")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+// layer failing all lookups.
+type failingLayer struct {
+	MemResource
+}
+
+func(fl failingLayer) GetTemplate(ctx context.Context, sym string) (string, error) {
+	return "", errors.New("template failed")
+}
+
+func(fl failingLayer) GetMenu(ctx context.Context, sym string) (string, error) {
+	return "", errors.New("menu failed")
+}
+
+func(fl failingLayer) GetCode(sym string) ([]byte, error) {
+	return nil, errors.New("code failed")
+}
+
+func TestLayeredResourceFallThrough(t *testing.T) {
+	rs := newTestLayers().WithLayer("broken", failingLayer{NewMemResource()})
+	ctx := context.Background()
+	r, err := rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "welcome to brand" {
+		t.Fatalf("unexpected template: %s", r)
+	}
+	r, err = rs.GetMenu(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "go on" {
+		t.Fatalf("unexpected menu: %s", r)
+	}
+	r, err = rs.GetMenu(ctx, "back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "back" {
+		t.Fatalf("unexpected menu: %s", r)
+	}
+	_, err = rs.GetCode("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx = context.WithValue(ctx, "Layers", []string{"brand", "broken"})
+	_, err = rs.GetMenu(ctx, "back")
+	if err == nil {
+		t.Fatalf("expected error of bottom layer")
+	}
+}
+
+func TestLayeredResourceDescribe(t *testing.T) {
+	base := NewMemResource()
+	fns := NewFuncRegistry()
+	fns.MustRegister(FuncInfo{
+		Sym: "bar",
+		Description: "base bar",
+		Func: func(ctx context.Context, sym string, input []byte) (Result, error) {
+			return Result{Content: "base bar"}, nil
+		},
+	})
+	base.WithFuncRegistry(fns)
+	brand := NewMemResource()
+	brand.AddEntryFunc("baz", func(ctx context.Context, sym string, input []byte) (Result, error) {
+		return Result{Content: "brand baz"}, nil
+	})
+	rs := NewLayeredResource().WithLayer("base", &base).WithLayer("brand", &brand)
+
+	var fd FuncDescriber = rs
+	info, err := fd.DescribeFunc("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Description != "base bar" {
+		t.Fatalf("unexpected description: %s", info.Description)
+	}
+	res, err := info.Func(context.Background(), "bar", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "base bar" {
+		t.Fatalf("unexpected content: %s", res.Content)
+	}
+	_, err = rs.DescribeFunc("nope")
+	var uerr *UnknownSymbolError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected unknown symbol error, got %v", err)
+	}
+}
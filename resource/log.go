@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("resource")
 )
+
+func init() {
+	logging.Register("resource", &Logg)
+}
@@ -3,6 +3,7 @@ package resource
 import (
 	"context"
 	"fmt"
+	"io/fs"
 )
 
 type MemResource struct {
@@ -10,18 +11,19 @@ type MemResource struct {
 	templates map[string]string
 	bytecodes map[string][]byte
 	menus map[string]string
-	funcs map[string]EntryFunc
+	funcs *FuncRegistry
 }
 
 func NewMemResource() MemResource {
 	mr := MemResource{
 		templates: make(map[string]string),
 		bytecodes: make(map[string][]byte),
-		funcs: make(map[string]EntryFunc),
+		menus: make(map[string]string),
+		funcs: NewFuncRegistry(),
 	}
 	mr.WithCodeGetter(mr.getCode)
 	mr.WithTemplateGetter(mr.getTemplate)
-	mr.WithEntryFuncGetter(mr.getFunc)
+	mr.WithEntryFuncGetter(mr.funcs.FuncFor)
 	mr.WithMenuGetter(mr.getMenu)
 	return mr
 }
@@ -37,7 +39,7 @@ func(mr MemResource) getTemplate(ctx context.Context, sym string) (string, error
 func(mr MemResource) getCode(sym string) ([]byte, error) {
 	r, ok := mr.bytecodes[sym]
 	if !ok {
-		return nil, fmt.Errorf("unknown bytecode: %s", sym)
+		return nil, fmt.Errorf("unknown bytecode: %s: %w", sym, fs.ErrNotExist)
 	}
 	return r, nil
 }
@@ -51,22 +53,37 @@ func(mr MemResource) getMenu(ctx context.Context, sym string) (string, error) {
 
 }
 
-func(mr MemResource) getFunc(sym string) (EntryFunc, error) {
-	r, ok := mr.funcs[sym]
-	if !ok {
-		return nil, fmt.Errorf("unknown entry func: %s", sym)
-	}
-	return r, nil
-}
-
 func(mr *MemResource) AddTemplate(sym string, tpl string) {
 	Logg.Tracef("mem resource added template", "sym", sym, "length", len(tpl))
 	mr.templates[sym] = tpl
 }
 
 
+func(mr *MemResource) AddMenu(sym string, label string) {
+	mr.menus[sym] = label
+}
+
 func(mr *MemResource) AddEntryFunc(sym string, fn EntryFunc) {
-	mr.funcs[sym] = fn
+	mr.funcs.Set(sym, fn)
+}
+
+// WithFuncRegistry sets the registry to resolve entry functions from.
+//
+// The registry replaces all entry functions added before, and may be shared with other resources.
+func(mr *MemResource) WithFuncRegistry(fns *FuncRegistry) *MemResource {
+	mr.funcs = fns
+	mr.WithEntryFuncGetter(fns.FuncFor)
+	return mr
+}
+
+// FuncRegistry returns the registry of entry functions used by the resource.
+func(mr MemResource) FuncRegistry() *FuncRegistry {
+	return mr.funcs
+}
+
+// DescribeFunc implements FuncDescriber interface.
+func(mr MemResource) DescribeFunc(sym string) (FuncInfo, error) {
+	return mr.funcs.DescribeFunc(sym)
 }
 
 func(mr *MemResource) AddBytecode(sym string, code []byte) {
@@ -3,7 +3,9 @@ package resource
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"testing"
 )
 
@@ -45,8 +47,8 @@ func TestMemResourceCode(t *testing.T) {
 	}
 
 	_, err = rs.GetCode("bar")
-	if err == nil {
-		t.Fatalf("expected error")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error, got %v", err)
 	}
 }
 
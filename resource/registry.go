@@ -0,0 +1,144 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UnknownSymbolError is returned when no entry function exists for a symbol.
+type UnknownSymbolError struct {
+	Sym string
+}
+
+// Error implements the error interface.
+func(e *UnknownSymbolError) Error() string {
+	return fmt.Sprintf("unknown entry func: %s", e.Sym)
+}
+
+// FuncInfo describes an entry function.
+type FuncInfo struct {
+	Sym string // Symbol the function is registered for.
+	Description string // Human readable description of the function.
+	MaxSize uint32 // Expected maximum size of the returned content. Zero if not known.
+	Flags []uint32 // Flags the function may set or reset.
+	MaxAge time.Duration // Time after which a loaded value should be refreshed. Zero if it is valid until freed.
+	Func EntryFunc
+}
+
+// FuncDescriber is implemented by resources that provide descriptions of their entry functions.
+type FuncDescriber interface {
+	DescribeFunc(sym string) (FuncInfo, error)
+}
+
+// FuncRegistry holds entry functions together with their descriptions.
+//
+// A registry can be shared between several resources. Namespace returns a view of the registry which prefixes all symbols.
+//
+// It is safe for concurrent use.
+type FuncRegistry struct {
+	mu *sync.RWMutex
+	funcs map[string]FuncInfo
+	prefix string
+}
+
+// NewFuncRegistry creates a new, empty FuncRegistry.
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{
+		mu: &sync.RWMutex{},
+		funcs: make(map[string]FuncInfo),
+	}
+}
+
+// Namespace returns a view of the registry in which all symbols are prefixed by <ns>_.
+//
+// Functions registered through the view are added to the shared registry. Lookups through the view only find functions in the namespace, by the symbol without the prefix.
+func(fr *FuncRegistry) Namespace(ns string) *FuncRegistry {
+	return &FuncRegistry{
+		mu: fr.mu,
+		funcs: fr.funcs,
+		prefix: fr.prefix + ns + "_",
+	}
+}
+
+// Register adds an entry function with its description.
+//
+// Fails if the symbol is empty, if the function is missing, or if a function is already registered for the symbol.
+func(fr *FuncRegistry) Register(info FuncInfo) error {
+	if info.Sym == "" {
+		return fmt.Errorf("entry func symbol missing")
+	}
+	if info.Func == nil {
+		return fmt.Errorf("entry func missing for sym: %s", info.Sym)
+	}
+	info.Sym = fr.prefix + info.Sym
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	_, ok := fr.funcs[info.Sym]
+	if ok {
+		return fmt.Errorf("entry func already registered for sym: %s", info.Sym)
+	}
+	fr.funcs[info.Sym] = info
+	return nil
+}
+
+// MustRegister is like Register, but panics on error.
+func(fr *FuncRegistry) MustRegister(info FuncInfo) {
+	err := fr.Register(info)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Set associates an entry function with a symbol without description, replacing any function already registered for the symbol.
+func(fr *FuncRegistry) Set(sym string, fn EntryFunc) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.funcs[fr.prefix + sym] = FuncInfo{
+		Sym: fr.prefix + sym,
+		Func: fn,
+	}
+}
+
+// DescribeFunc returns the description of the entry function registered for the symbol.
+//
+// Fails with UnknownSymbolError if no function is registered for the symbol.
+func(fr *FuncRegistry) DescribeFunc(sym string) (FuncInfo, error) {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+	info, ok := fr.funcs[fr.prefix + sym]
+	if !ok {
+		return FuncInfo{}, &UnknownSymbolError{Sym: fr.prefix + sym}
+	}
+	return info, nil
+}
+
+// FuncFor returns the entry function registered for the symbol.
+//
+// Fails with UnknownSymbolError if no function is registered for the symbol. It can be used as the entry function getter of a MenuResource.
+func(fr *FuncRegistry) FuncFor(sym string) (EntryFunc, error) {
+	info, err := fr.DescribeFunc(sym)
+	if err != nil {
+		return nil, err
+	}
+	return info.Func, nil
+}
+
+// Funcs returns the descriptions of all entry functions in the registry, or in the namespace of the view, ordered by symbol.
+func(fr *FuncRegistry) Funcs() []FuncInfo {
+	var r []FuncInfo
+	fr.mu.RLock()
+	for k, v := range fr.funcs {
+		if !strings.HasPrefix(k, fr.prefix) {
+			continue
+		}
+		r = append(r, v)
+	}
+	fr.mu.RUnlock()
+	sort.Slice(r, func(i int, j int) bool {
+		return r[i].Sym < r[j].Sym
+	})
+	return r
+}
@@ -0,0 +1,98 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func getNoop(ctx context.Context, sym string, input []byte) (Result, error) {
+	return Result{}, nil
+}
+
+func TestFuncRegistry(t *testing.T) {
+	fr := NewFuncRegistry()
+	err := fr.Register(FuncInfo{
+		Sym: "foo",
+		Description: "the foo",
+		MaxSize: 42,
+		Flags: []uint32{8, 9},
+		Func: getNoop,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fr.Register(FuncInfo{Sym: "foo", Func: getNoop})
+	if err == nil {
+		t.Fatalf("expected duplicate error")
+	}
+	err = fr.Register(FuncInfo{Sym: "bar"})
+	if err == nil {
+		t.Fatalf("expected missing func error")
+	}
+
+	acc := fr.Namespace("account")
+	acc.MustRegister(FuncInfo{Sym: "balance", Func: getNoop})
+	_, err = acc.FuncFor("balance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fr.FuncFor("account_balance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = acc.FuncFor("foo")
+	var e *UnknownSymbolError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected unknown symbol error, got: %v", err)
+	}
+	if e.Sym != "account_foo" {
+		t.Fatalf("unexpected symbol in error: %s", e.Sym)
+	}
+
+	r := fr.Funcs()
+	if len(r) != 2 || r[0].Sym != "account_balance" || r[1].Sym != "foo" {
+		t.Fatalf("unexpected funcs: %v", r)
+	}
+	if r[1].MaxSize != 42 || len(r[1].Flags) != 2 || r[1].Description != "the foo" {
+		t.Fatalf("unexpected info: %v", r[1])
+	}
+	r = acc.Funcs()
+	if len(r) != 1 || r[0].Sym != "account_balance" {
+		t.Fatalf("unexpected funcs in namespace: %v", r)
+	}
+}
+
+func TestFuncRegistryShared(t *testing.T) {
+	fr := NewFuncRegistry()
+	mr := NewMemResource()
+	mr.WithFuncRegistry(fr)
+	fs := NewFsysResource(newTestFsys()).WithFuncRegistry(fr)
+	fr.Set("bar", getNoop)
+
+	_, err := mr.FuncFor("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fs.FuncFor("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := fs.DescribeFunc("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Func == nil {
+		t.Fatalf("expected static content function")
+	}
+	_, err = mr.FuncFor("foo")
+	var e *UnknownSymbolError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected unknown symbol error, got: %v", err)
+	}
+	_, err = fs.FuncFor("baz")
+	if !errors.As(err, &e) {
+		t.Fatalf("expected unknown symbol error, got: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshotter is implemented by resources whose contents may change, and that can hand out a snapshot of their contents which does not change.
+type Snapshotter interface {
+	Snapshot() Resource // Get a snapshot of the current contents.
+}
+
+// ValidateFunc checks a new snapshot before it is used by a ReloadResource.
+type ValidateFunc func(rs Resource) error
+
+// ReloadResource serves a resource directory from an in-memory snapshot, and replaces the snapshot when the directory changes.
+//
+// A new snapshot is only used if all its bytecode is compatible with the vm, and it passes the optional validation function. Otherwise the previous snapshot remains in use.
+//
+// The Resource methods use the snapshot current at the time of each call. As ReloadResource implements Snapshotter, an engine created with it resolves all symbols from the snapshot current when the engine was created.
+type ReloadResource struct {
+	dir string
+	validate ValidateFunc
+	reload sync.Mutex // Serializes reloads.
+	mu sync.RWMutex
+	cur *BundleResource
+	sig string
+	fns *FuncRegistry
+}
+
+// NewReloadResource creates a new ReloadResource for the directory, and loads the first snapshot.
+//
+// The validate function may be nil.
+func NewReloadResource(dir string, validate ValidateFunc) (*ReloadResource, error) {
+	rr := &ReloadResource{
+		dir: dir,
+		validate: validate,
+		fns: NewFuncRegistry(),
+	}
+	_, err := rr.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+// Snapshot returns the current snapshot.
+//
+// The snapshot is not changed by later reloads.
+func(rr *ReloadResource) Snapshot() Resource {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	return rr.cur
+}
+
+// GetTemplate implements Resource interface.
+func(rr *ReloadResource) GetTemplate(ctx context.Context, sym string) (string, error) {
+	return rr.Snapshot().GetTemplate(ctx, sym)
+}
+
+// GetMenu implements Resource interface.
+func(rr *ReloadResource) GetMenu(ctx context.Context, sym string) (string, error) {
+	return rr.Snapshot().GetMenu(ctx, sym)
+}
+
+// GetCode implements Resource interface.
+func(rr *ReloadResource) GetCode(sym string) ([]byte, error) {
+	return rr.Snapshot().GetCode(sym)
+}
+
+// FuncFor implements Resource interface.
+func(rr *ReloadResource) FuncFor(sym string) (EntryFunc, error) {
+	return rr.Snapshot().FuncFor(sym)
+}
+
+// DescribeFunc implements FuncDescriber interface.
+func(rr *ReloadResource) DescribeFunc(sym string) (FuncInfo, error) {
+	rr.mu.RLock()
+	br := rr.cur
+	rr.mu.RUnlock()
+	return br.DescribeFunc(sym)
+}
+
+// AddLocalFunc associates an entry function with a symbol in the current and all later snapshots.
+func(rr *ReloadResource) AddLocalFunc(sym string, fn EntryFunc) {
+	rr.fns.Set(sym, fn)
+}
+
+// FuncRegistry returns the registry of entry functions shared by all snapshots.
+func(rr *ReloadResource) FuncRegistry() *FuncRegistry {
+	return rr.fns
+}
+
+// Reload replaces the snapshot if the directory has changed since the last snapshot.
+//
+// Changes are detected from the names, sizes and modification times of the resource files. Returns true if the snapshot was replaced.
+//
+// Concurrent reloads are performed one after the other, so that a snapshot is never replaced by an older one.
+func(rr *ReloadResource) Reload() (bool, error) {
+	rr.reload.Lock()
+	defer rr.reload.Unlock()
+	sig, err := dirSignature(rr.dir)
+	if err != nil {
+		return false, err
+	}
+	rr.mu.RLock()
+	same := sig == rr.sig
+	rr.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	b := bytes.NewBuffer(nil)
+	_, err = WriteBundle(b, rr.dir, nil)
+	if err != nil {
+		return false, err
+	}
+	br, err := NewBundleResourceFromReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		return false, err
+	}
+	br.WithFuncRegistry(rr.fns)
+	err = rr.check(br)
+	if err != nil {
+		return false, fmt.Errorf("snapshot of %s rejected: %w", rr.dir, err)
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.cur = br
+	rr.sig = sig
+	Logg.Infof("resource snapshot loaded", "dir", rr.dir, "files", len(br.Manifest.Files))
+	return true, nil
+}
+
+// Watch checks the directory for changes at the given interval, until the context is done.
+//
+// Failed reloads are logged, and retried at the next interval.
+func(rr *ReloadResource) Watch(ctx context.Context, interval time.Duration) {
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tc.C:
+			_, err := rr.Reload()
+			if err != nil {
+				Logg.Errorf("resource reload failed", "dir", rr.dir, "err", err)
+			}
+		}
+	}
+}
+
+// verify the bytecode of the snapshot, and apply the validation function.
+func(rr *ReloadResource) check(br *BundleResource) error {
+	for _, v := range br.Manifest.Files {
+		if !strings.HasSuffix(v.Name, ".bin") {
+			continue
+		}
+		_, err := br.GetCode(strings.TrimSuffix(v.Name, ".bin"))
+		if err != nil {
+			return err
+		}
+	}
+	if rr.validate != nil {
+		return rr.validate(br)
+	}
+	return nil
+}
+
+// String implements the String interface.
+func(rr *ReloadResource) String() string {
+	return fmt.Sprintf("reloading resource at path: %s", rr.dir)
+}
+
+// hash of the names, sizes and modification times of the resource files in the directory.
+func dirSignature(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var r []string
+	for _, v := range bundledEntries(entries) {
+		fi, err := v.Info()
+		if err != nil {
+			return "", err
+		}
+		r = append(r, fmt.Sprintf("%s %d %d", v.Name(), fi.Size(), fi.ModTime().UnixNano()))
+	}
+	sort.Strings(r)
+	h := sha256.Sum256([]byte(strings.Join(r, "\n")))
+	return fmt.Sprintf("%x", h), nil
+}
@@ -0,0 +1,143 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"git.defalsify.org/vise.git/bytecode"
+)
+
+func writeReloadFile(t *testing.T, dir string, name string, data []byte, age time.Duration) {
+	fp := path.Join(dir, name)
+	err := os.WriteFile(fp, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Now().Add(-age)
+	err = os.Chtimes(fp, ts, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadResource(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeReloadFile(t, dir, "root", []byte("hello"), time.Hour)
+	writeReloadFile(t, dir, "root.bin", []byte{0x00, 0x07}, time.Hour)
+	rs, err := NewReloadResource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.AddLocalFunc("foo", getTestFunc)
+	old := rs.Snapshot()
+
+	ok, err := rs.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("expected no reload of unchanged directory")
+	}
+
+	writeReloadFile(t, dir, "root", []byte("hello again"), 0)
+	ok, err = rs.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("expected reload")
+	}
+	r, err := rs.Snapshot().GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello again" {
+		t.Fatalf("unexpected template: %s", r)
+	}
+	r, err = rs.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello again" {
+		t.Fatalf("expected current snapshot used for lookup, got: %s", r)
+	}
+	r, err = old.GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello" {
+		t.Fatalf("expected old snapshot to be unchanged, got: %s", r)
+	}
+	_, err = rs.Snapshot().FuncFor("foo")
+	if err != nil {
+		t.Fatalf("expected local func in new snapshot: %v", err)
+	}
+
+	writeReloadFile(t, dir, "root.bin", bytecode.Encode(bytecode.Header{VmVersion: bytecode.VM_VERSION + 1}, []byte{0x00, 0x07}), 0)
+	writeReloadFile(t, dir, "root", []byte("broken"), 0)
+	_, err = rs.Reload()
+	var verr bytecode.VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+	r, err = rs.Snapshot().GetTemplate(ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello again" {
+		t.Fatalf("expected previous snapshot after failed reload, got: %s", r)
+	}
+}
+
+func TestReloadResourceValidate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeReloadFile(t, dir, "root", []byte("hello"), time.Hour)
+	writeReloadFile(t, dir, "root.bin", []byte{0x00, 0x07}, time.Hour)
+	validate := func(rs Resource) error {
+		_, err := rs.GetTemplate(ctx, "root")
+		return err
+	}
+	rs, err := NewReloadResource(dir, validate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Remove(path.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rs.Reload()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+
+	writeReloadFile(t, dir, "root", []byte("hello world"), 0)
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		rs.Watch(ctx, time.Millisecond)
+		close(done)
+	}()
+	for i := 0; i < 1000; i++ {
+		r, err := rs.Snapshot().GetTemplate(ctx, "root")
+		if err == nil && r == "hello world" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	r, err := rs.Snapshot().GetTemplate(context.Background(), "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "hello world" {
+		t.Fatalf("expected watch to reload, got: %s", r)
+	}
+}
@@ -2,6 +2,9 @@ package resource
 
 import (
 	"context"
+	"fmt"
+
+	"git.defalsify.org/vise.git/bytecode"
 )
 
 // Result contains the results of an external code operation.
@@ -73,8 +76,18 @@ func(m MenuResource) FuncFor(sym string) (EntryFunc, error) {
 }
 
 // GetCode implements Resource interface
+//
+// The container header is removed from the bytecode, if present. Fails if the bytecode was assembled for a different vm version.
 func(m MenuResource) GetCode(sym string) ([]byte, error) {
-	return m.codeFunc(sym)
+	b, err := m.codeFunc(sym)
+	if err != nil {
+		return nil, err
+	}
+	b, err = bytecode.Code(b)
+	if err != nil {
+		return nil, fmt.Errorf("bytecode for sym '%s': %w", sym, err)
+	}
+	return b, nil
 }
 
 // GetTemplate implements Resource interface
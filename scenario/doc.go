@@ -0,0 +1,2 @@
+// Package scenario runs declarative test scenarios of client inputs and expected outputs against vise resources.
+package scenario
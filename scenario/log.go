@@ -0,0 +1,13 @@
+package scenario
+
+import (
+	"git.defalsify.org/vise.git/logging"
+)
+
+var (
+	Logg logging.Logger = logging.NewVanilla().WithDomain("scenario")
+)
+
+func init() {
+	logging.Register("scenario", &Logg)
+}
@@ -0,0 +1,178 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"git.defalsify.org/vise.git/cache"
+	"git.defalsify.org/vise.git/engine"
+	"git.defalsify.org/vise.git/resource"
+	"git.defalsify.org/vise.git/state"
+)
+
+// StepResult holds the outcome of a single scenario step.
+type StepResult struct {
+	Index int
+	Input string
+	Output string // Actual output of the step.
+	Fail string // Description of the failure. Empty if the step passed.
+	Diff string // Line diff between expected and actual output, for failed exact matches.
+}
+
+// Report holds the outcome of all steps executed in a scenario.
+//
+// Execution stops at the first failed step.
+type Report struct {
+	Name string
+	Steps []StepResult
+}
+
+// Failed returns the step that failed, or nil if all steps passed.
+func(rp *Report) Failed() *StepResult {
+	for i, v := range rp.Steps {
+		if v.Fail != "" {
+			return &rp.Steps[i]
+		}
+	}
+	return nil
+}
+
+// String implements the String interface.
+func(rp *Report) String() string {
+	r := rp.Failed()
+	if r == nil {
+		return fmt.Sprintf("PASS %s (%d steps)", rp.Name, len(rp.Steps))
+	}
+	s := fmt.Sprintf("FAIL %s step %d (input '%s'): %s", rp.Name, r.Index, r.Input, r.Fail)
+	if r.Diff != "" {
+		s += "\n" + r.Diff
+	}
+	return s
+}
+
+// RunDir runs the scenario against a filesystem resource at the given directory.
+//
+// See Run.
+func RunDir(ctx context.Context, sc *Scenario, dir string) (*Report, error) {
+	return Run(ctx, sc, resource.NewFsResource(dir))
+}
+
+// Run executes all steps of the scenario in a new session, and checks the outcome of each step against its expectations.
+//
+// External symbols are resolved with the stubs defined in the scenario. Symbols not stubbed are resolved by the given resource.
+//
+// An error is only returned if the scenario could not be run. Failed expectations are recorded in the returned Report.
+func Run(ctx context.Context, sc *Scenario, rs resource.Resource) (*Report, error) {
+	rp := &Report{
+		Name: sc.Name,
+	}
+	srs := &stubResource{
+		Resource: rs,
+		fns: sc.Functions,
+	}
+	cfg := engine.Config{
+		Root: sc.Root,
+		SessionId: sc.Name,
+		OutputSize: sc.OutputSize,
+		FlagCount: sc.FlagCount,
+		CacheSize: sc.CacheSize,
+		Language: sc.Language,
+	}
+	ctx = context.WithValue(ctx, "SessionId", sc.Name)
+	st := state.NewState(sc.FlagCount)
+	ca := cache.NewCache().WithCacheSize(sc.CacheSize)
+	en := engine.NewEngine(ctx, cfg, &st, srs, ca)
+	for i, v := range sc.Steps {
+		var err error
+		srs.stepFns = v.Functions
+		r := StepResult{
+			Index: i,
+			Input: v.Input,
+		}
+		if i == 0 {
+			_, err = en.Init(ctx)
+		} else {
+			_, err = en.Exec(ctx, []byte(v.Input))
+		}
+		if err != nil {
+			r.Fail = fmt.Sprintf("execution error: %v", err)
+			rp.Steps = append(rp.Steps, r)
+			break
+		}
+		w := bytes.NewBuffer(nil)
+		_, err = en.WriteResult(ctx, w)
+		if err != nil {
+			r.Fail = fmt.Sprintf("render error: %v", err)
+			rp.Steps = append(rp.Steps, r)
+			break
+		}
+		r.Output = w.String()
+		check(&r, v, &st)
+		Logg.DebugCtxf(ctx, "scenario step done", "idx", i, "input", v.Input, "fail", r.Fail)
+		rp.Steps = append(rp.Steps, r)
+		if r.Fail != "" {
+			break
+		}
+	}
+	return rp, nil
+}
+
+// apply the expectations of the step to the execution outcome.
+func check(r *StepResult, step Step, st *state.State) {
+	switch step.Match {
+	case MATCH_CONTAINS:
+		if !strings.Contains(r.Output, step.Output) {
+			r.Fail = fmt.Sprintf("output does not contain '%s', got:\n%s", step.Output, r.Output)
+			return
+		}
+	case MATCH_REGEX:
+		re, err := regexp.Compile(step.Output)
+		if err != nil {
+			r.Fail = fmt.Sprintf("invalid output regex: %v", err)
+			return
+		}
+		if !re.MatchString(r.Output) {
+			r.Fail = fmt.Sprintf("output does not match /%s/, got:\n%s", step.Output, r.Output)
+			return
+		}
+	default:
+		if r.Output != step.Output {
+			r.Fail = "output mismatch"
+			r.Diff = engine.Diff(step.Output, r.Output)
+			return
+		}
+	}
+
+	if step.ExecPath != nil {
+		expect := strings.Join(step.ExecPath, "/")
+		have := strings.Join(st.ExecPath, "/")
+		if expect != have {
+			r.Fail = fmt.Sprintf("expected exec path '%s', got '%s'", expect, have)
+			return
+		}
+	}
+
+	for _, v := range step.FlagsSet {
+		if v >= st.BitSize {
+			r.Fail = fmt.Sprintf("flag %v out of range", v)
+			return
+		}
+		if !st.GetFlag(v) {
+			r.Fail = fmt.Sprintf("expected flag %v to be set", v)
+			return
+		}
+	}
+	for _, v := range step.FlagsReset {
+		if v >= st.BitSize {
+			r.Fail = fmt.Sprintf("flag %v out of range", v)
+			return
+		}
+		if st.GetFlag(v) {
+			r.Fail = fmt.Sprintf("expected flag %v not to be set", v)
+			return
+		}
+	}
+}
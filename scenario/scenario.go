@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"git.defalsify.org/vise.git/resource"
+)
+
+const (
+	MATCH_EXACT = "exact"
+	MATCH_CONTAINS = "contains"
+	MATCH_REGEX = "regex"
+)
+
+// Function defines the stubbed result of an external symbol.
+type Function struct {
+	Content string `json:"content"`
+	Status int `json:"status,omitempty"`
+	FlagSet []uint32 `json:"flag_set,omitempty"`
+	FlagReset []uint32 `json:"flag_reset,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Step defines a single client input and the expected outcome of its execution.
+type Step struct {
+	Input string `json:"input"` // Client input. Ignored for the first step, which initializes the session.
+	Output string `json:"output"` // Expected output.
+	Match string `json:"match,omitempty"` // How to match the output. One of MATCH_EXACT (default), MATCH_CONTAINS or MATCH_REGEX.
+	ExecPath []string `json:"exec_path,omitempty"` // If set, the expected node stack after execution.
+	FlagsSet []uint32 `json:"flags_set,omitempty"` // Flags that are expected to be set after execution.
+	FlagsReset []uint32 `json:"flags_reset,omitempty"` // Flags that are expected not to be set after execution.
+	Functions map[string]Function `json:"functions,omitempty"` // External symbol stubs overriding the scenario stubs for this step only.
+}
+
+// Scenario is a sequence of client inputs and expected outputs for a single session.
+type Scenario struct {
+	Name string `json:"name"`
+	Root string `json:"root,omitempty"` // Start node. Defaults to "root".
+	OutputSize uint32 `json:"output_size,omitempty"`
+	FlagCount uint32 `json:"flag_count,omitempty"`
+	CacheSize uint32 `json:"cache_size,omitempty"`
+	Language string `json:"language,omitempty"`
+	Functions map[string]Function `json:"functions,omitempty"` // External symbol stubs.
+	Steps []Step `json:"steps"`
+}
+
+// Read parses a JSON scenario definition.
+func Read(r io.Reader) (*Scenario, error) {
+	sc := &Scenario{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(sc)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Root == "" {
+		sc.Root = "root"
+	}
+	for i, v := range sc.Steps {
+		switch v.Match {
+		case "":
+			sc.Steps[i].Match = MATCH_EXACT
+		case MATCH_EXACT, MATCH_CONTAINS, MATCH_REGEX:
+		default:
+			return nil, fmt.Errorf("step %d: unknown match type: %s", i, v.Match)
+		}
+	}
+	return sc, nil
+}
+
+// ReadFile parses a JSON scenario definition from a file.
+//
+// If the scenario has no name, the file path is used.
+func ReadFile(fp string) (*Scenario, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fp, err)
+	}
+	if sc.Name == "" {
+		sc.Name = fp
+	}
+	return sc, nil
+}
+
+// resource resolving external symbols from scenario stubs before falling back to the wrapped resource.
+type stubResource struct {
+	resource.Resource
+	fns map[string]Function
+	stepFns map[string]Function
+}
+
+// FuncFor implements resource.Resource.
+func(sr *stubResource) FuncFor(sym string) (resource.EntryFunc, error) {
+	fn, ok := sr.stepFns[sym]
+	if !ok {
+		fn, ok = sr.fns[sym]
+	}
+	if !ok {
+		return sr.Resource.FuncFor(sym)
+	}
+	return func(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+		r := resource.Result{
+			Content: fn.Content,
+			Status: fn.Status,
+			FlagSet: fn.FlagSet,
+			FlagReset: fn.FlagReset,
+		}
+		if fn.Error != "" {
+			return r, fmt.Errorf("%s", fn.Error)
+		}
+		return r, nil
+	}, nil
+}
@@ -0,0 +1,123 @@
+package scenario
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/testdata"
+)
+
+const testScenario = `{
+	"name": "bar and back",
+	"flag_count": 1,
+	"cache_size": 1024,
+	"functions": {
+		"pinky": {
+			"content": "xyzzy",
+			"flag_set": [8]
+		}
+	},
+	"steps": [
+		{
+			"output": "hello world",
+			"match": "contains",
+			"exec_path": ["root"]
+		},
+		{
+			"input": "2",
+			"output": "this is bar - any input will return to top",
+			"exec_path": ["root", "bar"],
+			"flags_set": [8]
+		},
+		{
+			"input": "foo",
+			"output": "^hello world\\n",
+			"match": "regex",
+			"exec_path": ["root"]
+		}
+	]
+}`
+
+func TestRead(t *testing.T) {
+	sc, err := Read(strings.NewReader(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.Root != "root" {
+		t.Fatalf("expected default root, got %s", sc.Root)
+	}
+	if sc.Steps[1].Match != MATCH_EXACT {
+		t.Fatalf("expected default match exact, got %s", sc.Steps[1].Match)
+	}
+
+	_, err = Read(strings.NewReader(`{"steps": [{"match": "fuzzy"}]}`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	_, err = Read(strings.NewReader(`{"stepz": []}`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	dataDir, err := testdata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := Read(strings.NewReader(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp, err := RunDir(ctx, sc, dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Failed() != nil {
+		t.Fatal(rp)
+	}
+	if len(rp.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(rp.Steps))
+	}
+
+	sc.Steps[1].Output = "this is not bar"
+	rp, err = RunDir(ctx, sc, dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rp.Failed()
+	if r == nil {
+		t.Fatal("expected failure")
+	}
+	if r.Index != 1 {
+		t.Fatalf("expected step 1 to fail, got %d", r.Index)
+	}
+	expect := `- this is not bar
++ this is bar - any input will return to top`
+	if r.Diff != expect {
+		t.Fatalf("expected diff:\n%s\ngot:\n%s", expect, r.Diff)
+	}
+	if len(rp.Steps) != 2 {
+		t.Fatalf("expected run to stop at failed step, got %d steps", len(rp.Steps))
+	}
+
+	sc.Steps[1].Output = "this is bar - any input will return to top"
+	sc.Steps[1].Functions = map[string]Function{
+		"pinky": Function{
+			Content: "xyzzy",
+		},
+	}
+	rp, err = RunDir(ctx, sc, dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = rp.Failed()
+	if r == nil {
+		t.Fatal("expected failure")
+	}
+	if !strings.Contains(r.Fail, "flag 8") {
+		t.Fatalf("expected flag failure, got: %s", r.Fail)
+	}
+}
@@ -1,7 +1,10 @@
 package state
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 )
 
@@ -34,6 +37,51 @@ func(fd *flagDebugger) Register(flag uint32, name string) error {
 	return nil
 }
 
+// Name returns the name registered for a user flag.
+//
+// Names of internal flags are not returned.
+func(fd *flagDebugger) Name(flag uint32) (string, bool) {
+	if flag < FLAG_USERSTART {
+		return "", false
+	}
+	s, ok := fd.flagStrings[flag]
+	return s, ok
+}
+
+// Load registers the flag names declared in assembly source.
+//
+// Declarations have the form "FLAG <name> <flag>". All other lines are ignored, so that the same flag definition file can be included by the assembly code.
+func(fd *flagDebugger) Load(r io.Reader) error {
+	return readFlags(r, func(name string, flag uint32) error {
+		return fd.Register(flag, name)
+	})
+}
+
+// call fn for every flag declaration in the assembly source.
+func readFlags(r io.Reader, fn func(name string, flag uint32) error) error {
+	var i int
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		i++
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 || f[0] != "FLAG" {
+			continue
+		}
+		if len(f) != 3 {
+			return fmt.Errorf("line %d: FLAG expects name and flag", i)
+		}
+		v, err := strconv.ParseUint(f[2], 10, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid flag '%s'", i, f[2])
+		}
+		err = fn(f[1], uint32(v))
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i, err)
+		}
+	}
+	return sc.Err()
+}
+
 func(fd *flagDebugger) AsString(flags []byte, length uint32) string {
 	var r []string
 	var i uint32
@@ -2,6 +2,7 @@ package state
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +50,36 @@ func TestDebugState(t *testing.T) {
 		t.Fatalf("expected '%s', got '%s'", expect, r)
 	}
 }
+
+func TestDebugFlagLoad(t *testing.T) {
+	src := `# account flags
+FLAG accepted 13
+FLAG has_session 14
+
+LOAD foo 0
+`
+	err := FlagDebugger.Load(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := FlagDebugger.Name(14)
+	if !ok {
+		t.Fatalf("expected name for flag 14")
+	}
+	if s != "has_session" {
+		t.Fatalf("expected 'has_session', got '%s'", s)
+	}
+	_, ok = FlagDebugger.Name(FLAG_DIRTY)
+	if ok {
+		t.Fatalf("expected no name for internal flag")
+	}
+
+	err = FlagDebugger.Load(strings.NewReader("FLAG foo 3\n"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	err = FlagDebugger.Load(strings.NewReader("FLAG foo bar\n"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
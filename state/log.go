@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("state")
 )
+
+func init() {
+	logging.Register("state", &Logg)
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+)
+
+var (
+	flagNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// FlagRegistry allocates named user flags.
+//
+// The registry can be written as FLAG declarations for the assembler, so that the flags used by external code and the flags used in bytecode are defined in one place.
+type FlagRegistry struct {
+	flags map[string]uint32
+	names map[uint32]string
+	next uint32
+}
+
+// NewFlagRegistry creates a new, empty FlagRegistry.
+func NewFlagRegistry() *FlagRegistry {
+	return &FlagRegistry{
+		flags: make(map[string]uint32),
+		names: make(map[uint32]string),
+		next: FLAG_USERSTART,
+	}
+}
+
+// ReadFlagRegistry creates a FlagRegistry from the FLAG declarations in assembly source, as written by FlagRegistry.WriteTo.
+func ReadFlagRegistry(r io.Reader) (*FlagRegistry, error) {
+	fr := NewFlagRegistry()
+	err := readFlags(r, fr.Set)
+	if err != nil {
+		return nil, err
+	}
+	return fr, nil
+}
+
+// Add allocates the next free user flag for the given name.
+//
+// Fails if the name is invalid or already registered.
+func(fr *FlagRegistry) Add(name string) (uint32, error) {
+	for {
+		_, ok := fr.names[fr.next]
+		if !ok {
+			break
+		}
+		fr.next++
+	}
+	err := fr.Set(name, fr.next)
+	if err != nil {
+		return 0, err
+	}
+	return fr.next, nil
+}
+
+// MustAdd is like Add, but panics on error.
+//
+// It is intended for initialization of package level flag variables.
+func(fr *FlagRegistry) MustAdd(name string) uint32 {
+	flag, err := fr.Add(name)
+	if err != nil {
+		panic(err)
+	}
+	return flag
+}
+
+// Set registers the name for the given user flag.
+//
+// Fails if the flag is not a user flag, or if either name or flag is already registered. Registering the same name for the same flag again is not an error.
+func(fr *FlagRegistry) Set(name string, flag uint32) error {
+	if !flagNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid flag name '%s'", name)
+	}
+	if flag < FLAG_USERSTART {
+		return fmt.Errorf("flag %v is not definable by user", flag)
+	}
+	cur, ok := fr.flags[name]
+	if ok {
+		if cur == flag {
+			return nil
+		}
+		return fmt.Errorf("flag name '%s' already registered as %v", name, cur)
+	}
+	other, ok := fr.names[flag]
+	if ok {
+		return fmt.Errorf("flag %v already registered as '%s'", flag, other)
+	}
+	fr.flags[name] = flag
+	fr.names[flag] = name
+	return nil
+}
+
+// Flag returns the flag registered for the name.
+func(fr *FlagRegistry) Flag(name string) (uint32, error) {
+	flag, ok := fr.flags[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown flag name '%s'", name)
+	}
+	return flag, nil
+}
+
+// Name returns the name registered for the flag.
+func(fr *FlagRegistry) Name(flag uint32) (string, bool) {
+	name, ok := fr.names[flag]
+	return name, ok
+}
+
+// Flags returns all registered flags in ascending order.
+func(fr *FlagRegistry) Flags() []uint32 {
+	var r []uint32
+	for k := range fr.names {
+		r = append(r, k)
+	}
+	sort.Slice(r, func(i int, j int) bool {
+		return r[i] < r[j]
+	})
+	return r
+}
+
+// Count returns the number of user flags needed to hold all registered flags.
+//
+// The result can be used as the flag count for NewState.
+func(fr *FlagRegistry) Count() uint32 {
+	var r uint32
+	for k := range fr.names {
+		if k - FLAG_USERSTART + 1 > r {
+			r = k - FLAG_USERSTART + 1
+		}
+	}
+	return r
+}
+
+// WriteTo writes the registered flags as FLAG declarations for the assembler.
+//
+// It implements io.WriterTo.
+func(fr *FlagRegistry) WriteTo(w io.Writer) (int64, error) {
+	var r int64
+	for _, v := range fr.Flags() {
+		c, err := fmt.Fprintf(w, "FLAG %s %v\n", fr.names[v], v)
+		r += int64(c)
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// Debug registers the names of all registered flags with FlagDebugger.
+func(fr *FlagRegistry) Debug() error {
+	for _, v := range fr.Flags() {
+		err := FlagDebugger.Register(v, fr.names[v])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFlagRegistryAdd(t *testing.T) {
+	fr := NewFlagRegistry()
+	err := fr.Set("bar", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag, err := fr.Add("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag != 8 {
+		t.Fatalf("expected 8, got %v", flag)
+	}
+	flag, err = fr.Add("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag != 10 {
+		t.Fatalf("expected 10, got %v", flag)
+	}
+	flag, err = fr.Flag("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag != 9 {
+		t.Fatalf("expected 9, got %v", flag)
+	}
+	name, ok := fr.Name(10)
+	if !ok || name != "baz" {
+		t.Fatalf("expected 'baz', got '%s'", name)
+	}
+	if fr.Count() != 3 {
+		t.Fatalf("expected count 3, got %v", fr.Count())
+	}
+	_, err = fr.Flag("xyzzy")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFlagRegistryDuplicate(t *testing.T) {
+	fr := NewFlagRegistry()
+	fr.MustAdd("foo")
+	_, err := fr.Add("foo")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	err = fr.Set("bar", 8)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	err = fr.Set("foo", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fr.Set("baz", FLAG_LANG)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	_, err = fr.Add("1nvalid")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFlagRegistryWrite(t *testing.T) {
+	fr := NewFlagRegistry()
+	fr.Set("suspended", 12)
+	fr.MustAdd("accepted")
+	fr.MustAdd("has_session")
+	b := bytes.NewBuffer(nil)
+	_, err := fr.WriteTo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `FLAG accepted 8
+FLAG has_session 9
+FLAG suspended 12
+`
+	if b.String() != expect {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expect, b)
+	}
+
+	frr, err := ReadFlagRegistry(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag, err := frr.Flag("suspended")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag != 12 {
+		t.Fatalf("expected 12, got %v", flag)
+	}
+	if frr.Count() != 5 {
+		t.Fatalf("expected count 5, got %v", frr.Count())
+	}
+
+	err = fr.Debug()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, ok := FlagDebugger.Name(9)
+	if !ok || name != "has_session" {
+		t.Fatalf("expected 'has_session', got '%s'", name)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+
+	"git.defalsify.org/vise.git/state"
 )
 
 // ToString verifies all instructions in bytecode and returns an assmebly code instruction for it.
@@ -25,140 +27,146 @@ func ToString(b []byte) (string, error) {
 //
 // It fails on any parse error encountered before the bytecode EOF is reached.
 func ParseAll(b []byte, w io.Writer) (int, error) {
-	var s string
-	var rs string
 	var rn int
 	running := true
 	for running {
-		op, bb, err := opSplit(b)
+		rs, bb, err := ParseInstruction(b)
 		b = bb
 		if err != nil {
 			return rn, err
 		}
-		s = OpcodeString[op]
-		if s == "" {
-			return rn, fmt.Errorf("unknown opcode: %v", op)
-		}
-
-		switch op {
-		case CATCH:
-			r, n, m, bb, err := ParseCatch(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					vv := 0
-					if m {
-						vv = 1
-					}
-					if w != nil {
-						//rs = fmt.Sprintf("%s %s %v %v # invertmatch=%v\n", s, r, n, vv, m)
-						rs = fmt.Sprintf("%s %s %v %v\n", s, r, n, vv)
-					}
-				}
-			}
-		case CROAK:
-			n, m, bb, err := ParseCroak(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					vv := 0
-					if m {
-						vv = 1
-					}
-					//rs = fmt.Sprintf("%s %v %v # invertmatch=%v\n", s, n, vv, m)
-					rs = fmt.Sprintf("%s %v %v\n", s, n, vv)
-				}
-			}
-		case LOAD:
-			r, n, bb, err := ParseLoad(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					rs = fmt.Sprintf("%s %s %v\n", s, r, n)
-				}
-			}
-		case RELOAD:
-			r, bb, err := ParseReload(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					rs = fmt.Sprintf("%s %s\n", s, r)
-				}
-			}
-		case MAP:
-			r, bb, err := ParseMap(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					rs = fmt.Sprintf("%s %s\n", s, r)
-				}
-			}
-		case MOVE:
-			r, bb, err := ParseMove(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					rs = fmt.Sprintf("%s %s\n", s, r)
-				}
-			}
-		case INCMP:
-			r, v, bb, err := ParseInCmp(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					rs = fmt.Sprintf("%s %s %s\n", s, r, v)
-				}
-			}
-		case HALT:
-			b, err = ParseHalt(b)
-			rs = "HALT\n"
-		case MSINK:
-			b, err = ParseMSink(b)
-			rs = "MSINK\n"
-		case MOUT:
-			r, v, bb, err := ParseMOut(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					//rs = fmt.Sprintf("%s %s \"%s\"\n", s, r, v)
-					rs = fmt.Sprintf("%s %s %s\n", s, r, v)
-				}
-			}
-		case MNEXT:
-			r, v, bb, err := ParseMNext(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					//rs = fmt.Sprintf("%s %s \"%s\"\n", s, r, v)
-					rs = fmt.Sprintf("%s %s %s\n", s, r, v)
-				}
-			}
-		case MPREV:
-			r, v, bb, err := ParseMPrev(b)
-			b = bb
-			if err == nil {
-				if w != nil {
-					//rs = fmt.Sprintf("%s %s \"%s\"\n", s, r, v)
-					rs = fmt.Sprintf("%s %s %s\n", s, r, v)
-				}
-			}
-		}
-		if err != nil {
-			return rn, err
-		}
 		if w != nil {
-			n, err := io.WriteString(w, rs)
+			n, err := io.WriteString(w, rs + "\n")
 			if err != nil {
 				return rn, err
 			}
 			rn += n
-			Logg.Tracef("instruction debug write", "bytes", n, "instruction", s)
+			Logg.Tracef("instruction debug write", "bytes", n, "instruction", rs)
 		}
-
-		//rs += "\n"
 		if len(b) == 0 {
 			running = false
 		}
 	}
 	return rn, nil
 }
+
+// ParseInstruction parses and verifies the first instruction in the bytecode.
+//
+// It returns the instruction as an assembly code line string (without line terminator), and the remaining bytecode.
+func ParseInstruction(b []byte) (string, []byte, error) {
+	var rs string
+	op, b, err := opSplit(b)
+	if err != nil {
+		return "", b, err
+	}
+	s := OpcodeString[op]
+	if s == "" {
+		return "", b, fmt.Errorf("unknown opcode: %v", op)
+	}
+
+	switch op {
+	case CATCH:
+		r, n, m, bb, err := ParseCatch(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		vv := 0
+		if m {
+			vv = 1
+		}
+		rs = fmt.Sprintf("%s %s %v %v", s, r, n, vv) + flagComment(n)
+	case CROAK:
+		n, m, bb, err := ParseCroak(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		vv := 0
+		if m {
+			vv = 1
+		}
+		rs = fmt.Sprintf("%s %v %v", s, n, vv) + flagComment(n)
+	case LOAD:
+		r, n, bb, err := ParseLoad(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s %v", s, r, n)
+	case MAXAGE:
+		r, n, bb, err := ParseMaxAge(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s %v", s, r, n)
+	case RELOAD:
+		r, bb, err := ParseReload(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s", s, r)
+	case MAP:
+		r, bb, err := ParseMap(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s", s, r)
+	case MOVE:
+		r, bb, err := ParseMove(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s", s, r)
+	case INCMP:
+		r, v, bb, err := ParseInCmp(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s %s", s, r, v)
+	case HALT:
+		b, err = ParseHalt(b)
+		rs = s
+	case MSINK:
+		b, err = ParseMSink(b)
+		rs = s
+	case MOUT:
+		r, v, bb, err := ParseMOut(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s %s", s, r, v)
+	case MNEXT:
+		r, v, bb, err := ParseMNext(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s %s", s, r, v)
+	case MPREV:
+		r, v, bb, err := ParseMPrev(b)
+		if err != nil {
+			return "", bb, err
+		}
+		b = bb
+		rs = fmt.Sprintf("%s %s %s", s, r, v)
+	default:
+		rs = s
+	}
+	return rs, b, err
+}
+
+// assembly comment with the name of the flag, if registered with state.FlagDebugger.
+func flagComment(flag uint32) string {
+	s, ok := state.FlagDebugger.Name(flag)
+	if !ok {
+		return ""
+	}
+	return " # " + s
+}
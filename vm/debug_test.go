@@ -2,6 +2,8 @@ package vm
 
 import (
 	"testing"
+
+	"git.defalsify.org/vise.git/state"
 )
 
 
@@ -157,6 +159,27 @@ HALT
 	}
 }
 
+func TestToStringFlagName(t *testing.T) {
+	err := state.FlagDebugger.Register(42, "suspended")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewLine(nil, CATCH, []string{"aiee"}, []byte{0x2a}, []uint8{1})
+	b = NewLine(b, CROAK, nil, []byte{0x2a}, []uint8{0})
+	b = NewLine(b, CROAK, nil, []byte{0x2b}, []uint8{0})
+	r, err := ToString(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `CATCH aiee 42 1 # suspended
+CROAK 42 0 # suspended
+CROAK 43 0
+`
+	if r != expect {
+		t.Fatalf("expected:\n\t%v\ngot:\n\t%v", expect, r)
+	}
+}
+
 func TestVerifyMultiple(t *testing.T) {
 	b := NewLine(nil, INCMP, []string{"1", "foo"}, nil, nil)
 	b = NewLine(b, INCMP, []string{"2", "bar"}, nil, nil)
@@ -7,3 +7,7 @@ import (
 var (
 	Logg logging.Logger = logging.NewVanilla().WithDomain("vm")
 )
+
+func init() {
+	logging.Register("vm", &Logg)
+}
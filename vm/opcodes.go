@@ -1,6 +1,10 @@
 package vm
 
-const VERSION = 0
+import (
+	"git.defalsify.org/vise.git/bytecode"
+)
+
+const VERSION = bytecode.VM_VERSION
 
 type Opcode uint16
 
@@ -19,7 +23,8 @@ const (
 	MOUT = 10
 	MNEXT = 11
 	MPREV = 12
-	_MAX = 12
+	MAXAGE = 13
+	_MAX = 13
 )
 
 var (
@@ -37,6 +42,7 @@ var (
 		MOUT: "MOUT",
 		MNEXT: "MNEXT",
 		MPREV: "MPREV",
+		MAXAGE: "MAXAGE",
 	}
 
 	OpcodeIndex = map[string]Opcode {
@@ -53,6 +59,7 @@ var (
 		"MOUT": MOUT,
 		"MNEXT": MNEXT,
 		"MPREV": MPREV,
+		"MAXAGE": MAXAGE,
 	}
 
 )
@@ -2,8 +2,11 @@ package vm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"git.defalsify.org/vise.git/bytecode"
 	"git.defalsify.org/vise.git/cache"
 	"git.defalsify.org/vise.git/render"
 	"git.defalsify.org/vise.git/resource"
@@ -37,6 +40,12 @@ func(e ExternalCodeError) Error() string {
 	return fmt.Sprintf("error %v:%v", e.sym, e.code)
 }
 
+// InstructionFunc receives the assembly code representation of each instruction, right before it is executed by the vm.
+type InstructionFunc func(ctx context.Context, instruction string)
+
+// ResultFunc receives the result of every external symbol resolution performed by the vm.
+type ResultFunc func(ctx context.Context, sym string, r resource.Result, err error)
+
 // Vm holds sub-components mutated by the vm execution.
 // TODO: Renderer should be passed to avoid proxy methods not strictly related to vm operation
 type Vm struct {
@@ -46,6 +55,8 @@ type Vm struct {
 	mn *render.Menu // Menu component of page.
 	sizer *render.Sizer // Apply size constraints to output.
 	pg *render.Page // Render outputs with menues to size constraints.
+	instructionFunc InstructionFunc // Receives every instruction executed.
+	resultFunc ResultFunc // Receives every external symbol result.
 }
 
 // NewVm creates a new Vm.
@@ -62,6 +73,18 @@ func NewVm(st *state.State, rs resource.Resource, ca cache.Memory, sizer *render
 	return vmi
 }
 
+// WithInstructionFunc sets a function to receive every instruction executed.
+func(vmi *Vm) WithInstructionFunc(fn InstructionFunc) *Vm {
+	vmi.instructionFunc = fn
+	return vmi
+}
+
+// WithResultFunc sets a function to receive the result of every external symbol resolution.
+func(vmi *Vm) WithResultFunc(fn ResultFunc) *Vm {
+	vmi.resultFunc = fn
+	return vmi
+}
+
 // Reset re-initializes sub-components for output rendering.
 func(vmi *Vm) Reset() {
 	vmi.mn = render.NewMenu()
@@ -76,9 +99,16 @@ func(vmi *Vm) Reset() {
 //
 // Each step may update the state.
 //
+// Bytecode in a container is accepted if it was assembled for the same vm version.
+//
 // On error, the remaining instructions will be returned. State will not be rolled back.
 func(vm *Vm) Run(ctx context.Context, b []byte) ([]byte, error) {
 	Logg.Tracef("new vm run")
+	code, err := bytecode.Code(b)
+	if err != nil {
+		return b, err
+	}
+	b = code
 	running := true
 	for running {
 		r := vm.st.MatchFlag(state.FLAG_TERMINATE, true)
@@ -105,6 +135,12 @@ func(vm *Vm) Run(ctx context.Context, b []byte) ([]byte, error) {
 		}
 
 		_ = vm.st.SetFlag(state.FLAG_DIRTY)
+		if vm.instructionFunc != nil {
+			s, _, err := ParseInstruction(b)
+			if err == nil {
+				vm.instructionFunc(ctx, s)
+			}
+		}
 		op, bb, err := opSplit(b)
 		if err != nil {
 			return b, err
@@ -121,6 +157,8 @@ func(vm *Vm) Run(ctx context.Context, b []byte) ([]byte, error) {
 			b, err = vm.runLoad(ctx, b)
 		case RELOAD:
 			b, err = vm.runReload(ctx, b)
+		case MAXAGE:
+			b, err = vm.runMaxAge(ctx, b)
 		case MAP:
 			b, err = vm.runMap(ctx, b)
 		case MOVE:
@@ -218,6 +256,12 @@ func(vm *Vm) runDeadCheck(ctx context.Context, b []byte) ([]byte, error) {
 // executes the MAP opcode
 func(vm *Vm) runMap(ctx context.Context, b []byte) ([]byte, error) {
 	sym, b, err := ParseMap(b)
+	if vm.expired(ctx, sym) {
+		err = vm.refreshExpired(ctx, sym)
+		if err != nil {
+			return b, err
+		}
+	}
 	err = vm.pg.Map(sym)
 	return b, err
 }
@@ -248,7 +292,7 @@ func(vm *Vm) runCatch(ctx context.Context, b []byte) ([]byte, error) {
 		}
 		Logg.InfoCtxf(ctx, "catch!", "flag", sig, "sym", sym, "target", actualSym)
 		sym = actualSym
-		bh, err := vm.rs.GetCode(sym)
+		bh, err := vm.getCode(sym)
 		if err != nil {
 			return b, err
 		}
@@ -257,6 +301,15 @@ func(vm *Vm) runCatch(ctx context.Context, b []byte) ([]byte, error) {
 	return b, nil
 }
 
+// retrieve the instructions for the symbol, with the container header removed.
+func(vm *Vm) getCode(sym string) ([]byte, error) {
+	b, err := vm.rs.GetCode(sym)
+	if err != nil {
+		return nil, err
+	}
+	return bytecode.Code(b)
+}
+
 // executes the CROAK opcode
 func(vm *Vm) runCroak(ctx context.Context, b []byte) ([]byte, error) {
 	sig, mode, b, err := ParseCroak(b)
@@ -281,11 +334,15 @@ func(vm *Vm) runLoad(ctx context.Context, b []byte) ([]byte, error) {
 		return b, err
 	}
 	_, err = vm.ca.Get(sym)
-	if err == nil {
+	var serr *cache.SharedExpiredError
+	if err == nil || errors.As(err, &serr) {
+		if vm.expired(ctx, sym) {
+			return b, vm.refreshExpired(ctx, sym)
+		}
 		Logg.DebugCtxf(ctx, "skip already loaded symbol", "symbol", sym)
 		return b, nil
 	}
-	r, err := vm.refresh(sym, vm.rs, ctx)
+	r, err := vm.load(ctx, sym)
 	if err != nil {
 		return b, err
 	}
@@ -293,6 +350,21 @@ func(vm *Vm) runLoad(ctx context.Context, b []byte) ([]byte, error) {
 	return b, err
 }
 
+// executes the MAXAGE opcode
+func(vm *Vm) runMaxAge(ctx context.Context, b []byte) ([]byte, error) {
+	sym, age, b, err := ParseMaxAge(b)
+	if err != nil {
+		return b, err
+	}
+	am, ok := vm.ca.(cache.AgedMemory)
+	if !ok {
+		Logg.DebugCtxf(ctx, "cache does not support max age", "symbol", sym)
+		return b, nil
+	}
+	err = am.SetMaxAge(sym, time.Duration(age) * time.Second)
+	return b, err
+}
+
 // executes the RELOAD opcode
 func(vm *Vm) runReload(ctx context.Context, b []byte) ([]byte, error) {
 	sym, b, err := ParseReload(b)
@@ -304,6 +376,7 @@ func(vm *Vm) runReload(ctx context.Context, b []byte) ([]byte, error) {
 	if err != nil {
 		return b, err
 	}
+	vm.putShared(ctx, sym, r)
 	vm.ca.Update(sym, r)
 	if vm.pg != nil {
 		err := vm.pg.Map(sym)
@@ -324,7 +397,7 @@ func(vm *Vm) runMove(ctx context.Context, b []byte) ([]byte, error) {
 	if err != nil {
 		return b, err
 	}
-	code, err := vm.rs.GetCode(sym)
+	code, err := vm.getCode(sym)
 	if err != nil {
 		return b, err
 	}
@@ -386,7 +459,7 @@ func(vm *Vm) runInCmp(ctx context.Context, b []byte) ([]byte, error) {
 
 	vm.Reset()
 
-	code, err := vm.rs.GetCode(sym)
+	code, err := vm.getCode(sym)
 	if err != nil {
 		return b, err
 	}
@@ -478,6 +551,101 @@ func(vm *Vm) Render(ctx context.Context) (string, error) {
 	return r, nil
 }
 
+// PageCount returns the number of pages of the last rendered output.
+//
+// It is zero if the output was not paged.
+func(vm *Vm) PageCount() uint16 {
+	return vm.mn.PageCount()
+}
+
+// retrieve the value for a symbol, from the shared store if available.
+//
+// The result function is also called for values taken from the shared store.
+func(vm *Vm) load(ctx context.Context, sym string) (string, error) {
+	r, ok := vm.getShared(ctx, sym)
+	if ok {
+		if vm.resultFunc != nil {
+			vm.resultFunc(ctx, sym, resource.Result{Content: r}, nil)
+		}
+		return r, nil
+	}
+	r, err := vm.refresh(sym, vm.rs, ctx)
+	if err != nil {
+		return "", err
+	}
+	vm.putShared(ctx, sym, r)
+	return r, nil
+}
+
+// true if the loaded value of the symbol is older than its maximum age, or if its shared value has expired.
+//
+// The maximum age set with MAXAGE takes precedence over the maximum age of the entry function.
+func(vm *Vm) expired(ctx context.Context, sym string) bool {
+	var serr *cache.SharedExpiredError
+	_, err := vm.ca.Get(sym)
+	if errors.As(err, &serr) {
+		return true
+	}
+	am, ok := vm.ca.(cache.AgedMemory)
+	if !ok {
+		return false
+	}
+	maxAge, ok := am.MaxAge(sym)
+	if !ok {
+		fd, ok := vm.rs.(resource.FuncDescriber)
+		if !ok {
+			return false
+		}
+		info, err := fd.DescribeFunc(sym)
+		if err != nil {
+			return false
+		}
+		maxAge = info.MaxAge
+	}
+	if maxAge == 0 {
+		return false
+	}
+	age, err := am.Age(sym)
+	if err != nil {
+		return false
+	}
+	return age >= maxAge
+}
+
+// replace the loaded value of an expired symbol.
+func(vm *Vm) refreshExpired(ctx context.Context, sym string) error {
+	Logg.DebugCtxf(ctx, "refresh expired symbol", "symbol", sym)
+	r, err := vm.load(ctx, sym)
+	if err != nil {
+		return err
+	}
+	return vm.ca.Update(sym, r)
+}
+
+// retrieve the value of a symbol shared between sessions, if the cache supports it.
+func(vm *Vm) getShared(ctx context.Context, key string) (string, bool) {
+	sm, ok := vm.ca.(cache.SharedMemory)
+	if !ok {
+		return "", false
+	}
+	r, ok := sm.GetShared(key)
+	if ok {
+		Logg.DebugCtxf(ctx, "using shared value", "symbol", key)
+	}
+	return r, ok
+}
+
+// store the value of a symbol shared between sessions, if the cache supports it.
+func(vm *Vm) putShared(ctx context.Context, key string, val string) {
+	sm, ok := vm.ca.(cache.SharedMemory)
+	if !ok {
+		return
+	}
+	if sm.PutShared(key, val) {
+		Logg.DebugCtxf(ctx, "stored shared value", "symbol", key)
+	}
+}
+
 // retrieve and cache data for key
 func(vm *Vm) refresh(key string, rs resource.Resource, ctx context.Context) (string, error) {
 	var err error
@@ -491,6 +659,9 @@ func(vm *Vm) refresh(key string, rs resource.Resource, ctx context.Context) (str
 	}
 	input, _ := vm.st.GetInput()
 	r, err := fn(ctx, key, input)
+	if vm.resultFunc != nil {
+		vm.resultFunc(ctx, key, r, err)
+	}
 	if err != nil {
 		_ = vm.st.SetFlag(state.FLAG_LOADFAIL)
 		return "", NewExternalCodeError(key, err).WithCode(r.Status)
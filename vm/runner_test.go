@@ -3,10 +3,13 @@ package vm
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
+	"time"
 	
+	"git.defalsify.org/vise.git/bytecode"
 	"git.defalsify.org/vise.git/cache"
 	"git.defalsify.org/vise.git/render"
 	"git.defalsify.org/vise.git/resource"
@@ -730,3 +733,174 @@ func TestLoadError(t *testing.T) {
 		t.Fatalf("expected: \n\t%s\ngot:\n\t%s", expect, r)
 	}
 }
+
+func TestRunContainer(t *testing.T) {
+	st := state.NewState(5)
+	rs := NewTestResource(&st)
+	ca := cache.NewCache()
+	vm := NewVm(&st, &rs, ca, nil)
+
+	st.Down("bar")
+
+	ctx := context.TODO()
+	b := NewLine(nil, LOAD, []string{"one"}, []byte{0x0a}, nil)
+	b = NewLine(b, MOVE, []string{"baz"}, nil, nil)
+	rs.AddBytecode("baz", bytecode.Encode(bytecode.Header{VmVersion: VERSION}, NewLine(nil, HALT, nil, nil, nil)))
+	_, err := vm.Run(ctx, bytecode.Encode(bytecode.Header{VmVersion: VERSION}, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := st.Where()
+	if r != "baz" {
+		t.Fatalf("expected 'baz', got '%s'", r)
+	}
+
+	_, err = vm.Run(ctx, bytecode.Encode(bytecode.Header{VmVersion: VERSION + 1}, b))
+	var verr bytecode.VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+
+	b = NewLine(nil, MOVE, []string{"xyzzy"}, nil, nil)
+	rs.AddBytecode("xyzzy", bytecode.Encode(bytecode.Header{VmVersion: VERSION + 1}, NewLine(nil, HALT, nil, nil, nil)))
+	_, err = vm.Run(ctx, b)
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func TestRunLoadShared(t *testing.T) {
+	ss := cache.NewSharedStore()
+	ss.Declare("dyn", time.Hour)
+	ctx := context.TODO()
+	b := NewLine(nil, LOAD, []string{"dyn"}, []byte{0x0a}, nil)
+	b = NewLine(b, HALT, nil, nil, nil)
+	defer func() {
+		dynVal = "three"
+	}()
+
+	dynVal = "foo"
+	st := state.NewState(5)
+	rs := NewTestResource(&st)
+	ca := cache.NewCache().WithSharedStore(ss)
+	vm := NewVm(&st, &rs, ca, nil)
+	_, err := vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dynVal = "barbar"
+	st = state.NewState(5)
+	ca = cache.NewCache().WithSharedStore(ss).WithCacheSize(5)
+	var results []string
+	vm = NewVm(&st, &rs, ca, nil).WithResultFunc(func(ctx context.Context, sym string, r resource.Result, err error) {
+		results = append(results, r.Content)
+	})
+	_, err = vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0] != "foo" {
+		t.Fatalf("expected shared value passed to result func, got %v", results)
+	}
+	if ca.Cache[0]["dyn"] != "" {
+		t.Fatalf("expected shared value not to be copied to frame")
+	}
+	r, err := ca.Get("dyn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "foo" {
+		t.Fatalf("expected shared value 'foo', got '%s'", r)
+	}
+	if ca.CacheUseSize != 3 {
+		t.Fatalf("expected shared value counted in cache use, got %d", ca.CacheUseSize)
+	}
+
+	dynVal = "bar"
+	ss.Expire("dyn")
+	_, err = vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = ca.Get("dyn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "bar" {
+		t.Fatalf("expected expired shared value reloaded, got '%s'", r)
+	}
+	dynVal = "barbar"
+
+	ss.Expire("dyn")
+	st = state.NewState(5)
+	ca = cache.NewCache().WithSharedStore(ss).WithCacheSize(5)
+	vm = NewVm(&st, &rs, ca, nil)
+	_, err = vm.Run(ctx, b)
+	if err == nil {
+		t.Fatalf("expected capacity error")
+	}
+	r, ok := ss.Get("dyn")
+	if !ok || r != "barbar" {
+		t.Fatalf("expected refreshed shared value 'barbar', got '%s'", r)
+	}
+}
+
+func TestRunLoadMaxAge(t *testing.T) {
+	st := state.NewState(5)
+	rs := NewTestResource(&st)
+	fns := resource.NewFuncRegistry()
+	fns.MustRegister(resource.FuncInfo{
+		Sym: "dyn",
+		MaxAge: time.Nanosecond,
+		Func: getDyn,
+	})
+	rs.WithFuncRegistry(fns)
+	ca := cache.NewCache()
+	vm := NewVm(&st, &rs, ca, nil)
+	ctx := context.TODO()
+	defer func() {
+		dynVal = "three"
+	}()
+
+	b := NewLine(nil, LOAD, []string{"dyn"}, []byte{0x0a}, nil)
+	b = NewLine(b, HALT, nil, nil, nil)
+	dynVal = "foo"
+	_, err := vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	dynVal = "bar"
+	_, err = vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := ca.Get("dyn")
+	if r != "bar" {
+		t.Fatalf("expected refreshed value 'bar', got '%s'", r)
+	}
+
+	b = NewLine(nil, LOAD, []string{"dyn"}, []byte{0x0a}, nil)
+	b = NewLine(b, MAXAGE, []string{"dyn"}, []byte{0x0e, 0x10}, nil)
+	b = NewLine(b, HALT, nil, nil, nil)
+	time.Sleep(time.Millisecond)
+	dynVal = "baz"
+	_, err = vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynVal = "xyzzy"
+	_, err = vm.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ = ca.Get("dyn")
+	if r != "baz" {
+		t.Fatalf("expected value 'baz' within max age, got '%s'", r)
+	}
+	maxAge, _ := ca.MaxAge("dyn")
+	if maxAge != time.Hour {
+		t.Fatalf("expected max age %v, got %v", time.Hour, maxAge)
+	}
+}
@@ -40,6 +40,11 @@ func ParseLoad(b []byte) (string, uint32, []byte, error) {
 	return parseSymLen(b)
 }
 
+// ParseMaxAge parses and extracts the expected argument portion of a MAXAGE instruction
+func ParseMaxAge(b []byte) (string, uint32, []byte, error) {
+	return parseSymLen(b)
+}
+
 // ParseReload parses and extracts the expected argument portion of a RELOAD instruction
 func ParseReload(b []byte) (string, []byte, error) {
 	return parseSym(b)